@@ -1,27 +1,67 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
 
 	"github.com/LocalLink/internal/api"
+	"github.com/LocalLink/internal/auth"
 	"github.com/LocalLink/internal/config"
 	"github.com/LocalLink/internal/database"
+	"github.com/LocalLink/internal/geocode"
+	"github.com/LocalLink/internal/jobs"
+	"github.com/LocalLink/internal/mail"
+	"github.com/LocalLink/internal/storage"
 	"github.com/LocalLink/internal/websocket"
 )
 
 func main() {
 	cfg := config.Load()
 
+	keys, err := auth.NewKeySet(cfg)
+	if err != nil {
+		log.Fatalf("Failed to load signing keys: %v", err)
+	}
+
 	dbPool := database.Connect(cfg.DatabaseURL)
 	defer dbPool.Close()
 	store := database.NewStore(dbPool)
 
+	blobs, err := storage.NewLocalStore(cfg.UploadDir)
+	if err != nil {
+		log.Fatalf("Failed to open upload directory: %v", err)
+	}
+
+	var geocoder geocode.Geocoder = geocode.Disabled{}
+	if cfg.GazetteerPath != "" {
+		gazetteer, err := geocode.OpenGazetteer(cfg.GazetteerPath)
+		if err != nil {
+			log.Fatalf("Failed to load gazetteer: %v", err)
+		}
+		log.Printf("Loaded %d places for geocoding", gazetteer.Len())
+		geocoder = gazetteer
+	}
+
+	var mailer mail.Sender = mail.LogSender{}
+	if cfg.SMTPAddr != "" {
+		mailer = mail.SMTPSender{Addr: cfg.SMTPAddr, Username: cfg.SMTPUsername, Password: cfg.SMTPPassword, From: cfg.MailFrom}
+	}
+
 	hub := websocket.NewHub()
 	go hub.Run()
 
-	router := api.NewRouter(store, cfg, hub)
+	jobs.Start(context.Background(),
+		jobs.AccountDeletion(store),
+		jobs.ReservationSweeper(store),
+		jobs.BatchExpiry(store),
+		jobs.PreorderRelease(store, hub),
+		jobs.Subscriptions(store, hub),
+		jobs.SearchDigests(store, mailer),
+	)
+
+	router := api.NewRouter(store, cfg, keys, hub, blobs, geocoder)
 
 	serverAddr := ":8080"
 	fmt.Printf("Starting server on %s\n", serverAddr)
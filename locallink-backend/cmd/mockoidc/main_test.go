@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/LocalLink/internal/config"
+	"github.com/LocalLink/internal/oidc"
+)
+
+const testRedirectURL = "http://api.test/auth/oidc/local/callback"
+
+// startProvider serves a mock provider on a test server whose URL is its issuer.
+func startProvider(t *testing.T) *provider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &provider{
+		clientID:      "locallink",
+		subject:       "mock-user-1",
+		email:         "buyer@example.com",
+		name:          "Mock Buyer",
+		emailVerified: true,
+		key:           key,
+		codes:         make(map[string]pendingCode),
+	}
+	srv := httptest.NewServer(p.routes())
+	t.Cleanup(srv.Close)
+	p.issuer = srv.URL
+	return p
+}
+
+// authorize starts a login the way StartOIDCLogin does, follows the browser to the
+// provider and returns the code and state it redirects back to the callback with.
+func authorize(t *testing.T, client *oidc.Provider, state, nonce, verifier string) (code, returnedState string) {
+	t.Helper()
+	authURL, err := client.AuthCodeURL(context.Background(), state, nonce, verifier)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	browser := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := browser.Get(authURL)
+	if err != nil {
+		t.Fatalf("GET authorize: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize returned %s, want 302", resp.Status)
+	}
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := callback.Scheme + "://" + callback.Host + callback.Path; got != testRedirectURL {
+		t.Fatalf("redirected to %s, want %s", got, testRedirectURL)
+	}
+	return callback.Query().Get("code"), callback.Query().Get("state")
+}
+
+func newClient(p *provider) *oidc.Provider {
+	return oidc.NewProvider("local", config.OIDCProvider{
+		IssuerURL:   p.issuer,
+		ClientID:    p.clientID,
+		RedirectURL: testRedirectURL,
+		Scopes:      []string{"openid", "email", "profile"},
+	})
+}
+
+func TestLoginAndCallback(t *testing.T) {
+	p := startProvider(t)
+	client := newClient(p)
+
+	code, state := authorize(t, client, "state-1", "nonce-1", "verifier-1")
+	if state != "state-1" {
+		t.Fatalf("callback state = %q, want state-1", state)
+	}
+	identity, err := client.Exchange(context.Background(), code, "verifier-1", "nonce-1")
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if identity.Subject != p.subject || identity.Email != p.email || !identity.EmailVerified || identity.Name != p.name {
+		t.Errorf("identity = %+v, want the provider's configured user", identity)
+	}
+
+	// Codes are single-use.
+	if _, err := client.Exchange(context.Background(), code, "verifier-1", "nonce-1"); err == nil {
+		t.Error("second Exchange of the same code succeeded")
+	}
+}
+
+func TestCallbackRejectsTamperedLogin(t *testing.T) {
+	tests := []struct {
+		name     string
+		verifier string
+		nonce    string
+	}{
+		{"wrong PKCE verifier", "other-verifier", "nonce-1"},
+		{"wrong nonce", "verifier-1", "other-nonce"},
+	}
+	p := startProvider(t)
+	client := newClient(p)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _ := authorize(t, client, "state-1", "nonce-1", "verifier-1")
+			if _, err := client.Exchange(context.Background(), code, tt.verifier, tt.nonce); err == nil {
+				t.Error("Exchange succeeded")
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/LocalLink/internal/auth"
+	"github.com/LocalLink/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Account Data Handlers
+func (h *Handler) ExportUserData(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	export, err := h.buildUserExport(r, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to export account data")
+		return
+	}
+
+	filename := fmt.Sprintf("locallink-export-%d-%s", userID, export.ExportedAt.Format("20060102"))
+	if r.URL.Query().Get("format") != "zip" {
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`.json"`)
+		respondWithJSON(w, http.StatusOK, export)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`.zip"`)
+	w.WriteHeader(http.StatusOK)
+	archive := zip.NewWriter(w)
+	files := []struct {
+		name    string
+		payload interface{}
+	}{
+		{"profile.json", export.Profile},
+		{"identities.json", export.Identities},
+		{"api_keys.json", export.APIKeys},
+		{"orders.json", export.Orders},
+		{"reviews.json", export.Reviews},
+		{"producer_profile.json", export.ProducerProfile},
+		{"saved_searches.json", export.SavedSearches},
+	}
+	for _, file := range files {
+		f, err := archive.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Deflate, Modified: export.ExportedAt})
+		if err != nil {
+			return
+		}
+		encoder := json.NewEncoder(f)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.payload); err != nil {
+			return
+		}
+	}
+	archive.Close()
+}
+
+func (h *Handler) buildUserExport(r *http.Request, userID int) (*models.UserExport, error) {
+	ctx := r.Context()
+	user, err := h.store.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	export := &models.UserExport{ExportedAt: time.Now().UTC(), Profile: *user}
+	if export.Identities, err = h.store.GetIdentitiesForUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	if export.APIKeys, err = h.store.GetAPIKeysForUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	if export.Orders, err = h.store.GetOrdersForUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	if export.Reviews, err = h.store.GetReviewsByUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	if export.SavedSearches, err = h.store.GetSavedSearchesForUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	if profile, err := h.store.GetProducerProfile(ctx, userID); err == nil {
+		export.ProducerProfile = profile
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	return export, nil
+}
+
+// DeleteUserAccount schedules the account for anonymisation once the grace period ends.
+func (h *Handler) DeleteUserAccount(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	scheduledFor := time.Now().Add(h.cfg.AccountDeletionGracePeriod)
+	if err := h.store.ScheduleUserDeletion(r.Context(), userID, scheduledFor); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to schedule account deletion")
+		return
+	}
+	respondWithJSON(w, http.StatusAccepted, map[string]time.Time{"deletionScheduledFor": scheduledFor})
+}
+
+func (h *Handler) CancelAccountDeletion(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	if err := h.store.CancelUserDeletion(r.Context(), userID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to cancel account deletion")
+		return
+	}
+	user, err := h.store.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, user)
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/LocalLink/internal/auth"
+	"github.com/LocalLink/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// API Key Handlers
+func (h *Handler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	user, err := h.store.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	if user.Role != "producer" {
+		respondWithError(w, http.StatusForbidden, "Only producers can create API keys")
+		return
+	}
+	var input models.CreateAPIKeyInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		respondWithError(w, http.StatusBadRequest, "A name is required")
+		return
+	}
+	if len(input.Scopes) == 0 {
+		respondWithError(w, http.StatusBadRequest, "At least one scope is required")
+		return
+	}
+	for _, scope := range input.Scopes {
+		if !auth.ValidScopes[scope] {
+			respondWithError(w, http.StatusBadRequest, "Unknown scope: "+scope)
+			return
+		}
+	}
+
+	plaintext, prefix, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to generate API key")
+		return
+	}
+	key := models.APIKey{UserID: userID, Name: input.Name, Prefix: prefix, KeyHash: hash, Scopes: input.Scopes}
+	if err := h.store.CreateAPIKey(r.Context(), &key); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create API key")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, map[string]interface{}{"apiKey": key, "key": plaintext})
+}
+
+func (h *Handler) GetAPIKeys(w http.ResponseWriter, r *http.Request) {
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+	keys, err := h.store.GetAPIKeysForUser(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Could not fetch API keys")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, keys)
+}
+
+func (h *Handler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+	keyID, _ := strconv.Atoi(chi.URLParam(r, "keyID"))
+	revoked, err := h.store.RevokeAPIKey(r.Context(), userID, keyID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to revoke API key")
+		return
+	}
+	if !revoked {
+		respondWithError(w, http.StatusNotFound, "API key not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/LocalLink/internal/database"
+	"github.com/LocalLink/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const dateLayout = "2006-01-02"
+
+// Product Batch Handlers
+func (h *Handler) CreateProductBatch(w http.ResponseWriter, r *http.Request) {
+	product, ok := h.ownedProduct(w, r)
+	if !ok {
+		return
+	}
+	if len(product.Variants) > 0 {
+		respondWithError(w, http.StatusConflict, "Batches cannot be used for products with variants")
+		return
+	}
+	if product.Preorder || product.PreorderedQuantity > 0 {
+		respondWithError(w, http.StatusConflict, "Batches cannot be added while the product has pre-orders waiting")
+		return
+	}
+	var input models.CreateBatchInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if input.Quantity <= 0 {
+		respondWithError(w, http.StatusBadRequest, "Batch quantity must be positive")
+		return
+	}
+	bestBefore, err := time.Parse(dateLayout, input.BestBefore)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "bestBefore must be a date in YYYY-MM-DD format")
+		return
+	}
+	batch := models.ProductBatch{ProductID: product.ID, Quantity: input.Quantity, BestBefore: bestBefore}
+	if input.HarvestedOn != "" {
+		harvestedOn, err := time.Parse(dateLayout, input.HarvestedOn)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "harvestedOn must be a date in YYYY-MM-DD format")
+			return
+		}
+		if harvestedOn.After(bestBefore) {
+			respondWithError(w, http.StatusBadRequest, "harvestedOn cannot be after bestBefore")
+			return
+		}
+		batch.HarvestedOn = &harvestedOn
+	}
+	if err := h.store.CreateProductBatch(r.Context(), &batch); err != nil {
+		if errors.Is(err, database.ErrUntrackedStock) {
+			respondWithError(w, http.StatusConflict, "Set the product's stock to 0 before tracking it in batches")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to create batch")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, batch)
+}
+
+func (h *Handler) DeleteProductBatch(w http.ResponseWriter, r *http.Request) {
+	product, ok := h.ownedProduct(w, r)
+	if !ok {
+		return
+	}
+	batchID, _ := strconv.Atoi(chi.URLParam(r, "batchID"))
+	batch, err := h.store.GetProductBatch(r.Context(), batchID)
+	if err != nil || batch.ProductID != product.ID {
+		respondWithError(w, http.StatusNotFound, "Batch not found")
+		return
+	}
+	if err := h.store.DeleteProductBatch(r.Context(), batchID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete batch")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func validateMarkdowns(markdowns []models.Markdown) string {
+	for _, m := range markdowns {
+		if m.DaysBefore < 0 {
+			return "Markdown daysBefore cannot be negative"
+		}
+		if m.PercentOff <= 0 || m.PercentOff >= 100 {
+			return fmt.Sprintf("Markdown percentOff must be between 0 and 100, got %g", m.PercentOff)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,201 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/LocalLink/internal/auth"
+	"github.com/LocalLink/internal/catalog"
+	"github.com/LocalLink/internal/models"
+)
+
+const maxImportBytes = 5 << 20
+
+// ImportReport summarises a catalogue import. When Errors is non-empty nothing was
+// written.
+type ImportReport struct {
+	DryRun  bool               `json:"dryRun"`
+	Rows    int                `json:"rows"`
+	Created int                `json:"created"`
+	Updated int                `json:"updated"`
+	Errors  []catalog.RowError `json:"errors"`
+}
+
+// Catalogue Handlers
+
+// ImportProducts upserts products by SKU from a CSV or JSON Lines body. With
+// ?dryRun=true the file is only validated and the report says what would change.
+func (h *Handler) ImportProducts(w http.ResponseWriter, r *http.Request) {
+	producerID, _ := auth.GetUserIDFromContext(r.Context())
+	format := catalogFormat(r, r.Header.Get("Content-Type"))
+	if format == "" {
+		respondWithError(w, http.StatusUnsupportedMediaType, "Send the catalogue as text/csv or application/x-ndjson, or set ?format=csv|jsonl")
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+	rows, rowErrors, err := catalog.Read(r.Body, format)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			respondWithError(w, http.StatusRequestEntityTooLarge, "Import file is too large")
+			return
+		}
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	totalRows := len(rows) + len(rowErrors)
+	rowErrors = append(rowErrors, catalog.Validate(rows)...)
+
+	existing, err := h.store.GetProductsByProducer(r.Context(), producerID, true)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	bySKU := make(map[string]models.Product)
+	for _, p := range existing {
+		if p.SKU != "" {
+			bySKU[p.SKU] = p
+		}
+	}
+
+	report := ImportReport{DryRun: r.URL.Query().Get("dryRun") == "true", Rows: totalRows}
+	imports := make([]models.ProductImport, 0, len(rows))
+	for _, row := range rows {
+		p := row.Product
+		item := models.ProductImport{HasLocation: row.HasLocation}
+		current, exists := bySKU[p.SKU]
+		if exists {
+			report.Updated++
+			item.Update = importUpdate(row)
+			if row.Given["quantity"] && current.BatchTracked {
+				rowErrors = append(rowErrors, catalog.RowError{Line: row.Line, SKU: p.SKU, Message: "quantity is managed through this product's batches; leave it empty"})
+			}
+			if current.Status == models.ProductStatusArchived && (p.Status == "" || p.Status == models.ProductStatusArchived) {
+				rowErrors = append(rowErrors, catalog.RowError{Line: row.Line, SKU: p.SKU, Message: "this product is archived; set its status to restore it first"})
+			}
+		} else {
+			report.Created++
+			if !row.HasLocation && p.SKU != "" {
+				rowErrors = append(rowErrors, catalog.RowError{Line: row.Line, SKU: p.SKU, Message: "latitude and longitude are required for new products"})
+			}
+			if p.Status == models.ProductStatusArchived {
+				rowErrors = append(rowErrors, catalog.RowError{Line: row.Line, SKU: p.SKU, Message: "status must be draft, published or paused for new products"})
+			}
+			if p.Status == "" {
+				p.Status = models.ProductStatusPublished
+			}
+			if p.Unit == "" {
+				p.Unit = "each"
+			}
+			if p.QuantityStep == 0 {
+				p.QuantityStep = 1
+			}
+		}
+		item.Product = p
+		imports = append(imports, item)
+	}
+
+	report.Errors = rowErrors
+	if report.Errors == nil {
+		report.Errors = []catalog.RowError{}
+	}
+	if len(rowErrors) > 0 {
+		report.Created, report.Updated = 0, 0
+		respondWithJSON(w, http.StatusUnprocessableEntity, report)
+		return
+	}
+	if report.DryRun {
+		respondWithJSON(w, http.StatusOK, report)
+		return
+	}
+	if report.Created, report.Updated, err = h.store.ImportProducts(r.Context(), producerID, imports); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to import products")
+		return
+	}
+	for _, item := range imports {
+		current, existed := bySKU[item.Product.SKU]
+		if item.Product.Status == models.ProductStatusPublished && (!existed || current.Status != models.ProductStatusPublished) {
+			h.notifySavedSearches(r.Context(), item.Product.ID)
+		}
+	}
+	respondWithJSON(w, http.StatusOK, report)
+}
+
+// importUpdate takes the fields an import row gave for an existing product. Name
+// and price are required on every row; the rest are left alone when not given.
+func importUpdate(row catalog.Row) models.UpdateProductInput {
+	p := row.Product
+	input := models.UpdateProductInput{Name: &p.Name, Price: &p.Price}
+	if row.Given["description"] {
+		input.Description = &p.Description
+	}
+	if row.Given["quantity"] {
+		input.Quantity = &p.Quantity
+	}
+	if row.Given["unit"] {
+		input.Unit = &p.Unit
+	}
+	if row.Given["quantity_step"] {
+		input.QuantityStep = &p.QuantityStep
+	}
+	if row.Given["status"] {
+		input.Status = &p.Status
+	}
+	return input
+}
+
+// ExportProducts writes the producer's catalogue in the import format. Archived
+// products are left out unless ?includeArchived=true.
+func (h *Handler) ExportProducts(w http.ResponseWriter, r *http.Request) {
+	producerID, _ := auth.GetUserIDFromContext(r.Context())
+	format := catalogFormat(r, r.Header.Get("Accept"))
+	if format == "" {
+		format = catalog.FormatCSV
+	}
+	products, err := h.store.GetProductsByProducer(r.Context(), producerID, r.URL.Query().Get("includeArchived") == "true")
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to export products")
+		return
+	}
+
+	var body bytes.Buffer
+	if err := catalog.Write(&body, format, products); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to export products")
+		return
+	}
+
+	contentType := "text/csv; charset=utf-8"
+	if format == catalog.FormatJSONL {
+		contentType = "application/x-ndjson"
+	}
+	filename := fmt.Sprintf("locallink-products-%d-%s.%s", producerID, time.Now().UTC().Format("20060102"), format)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.WriteHeader(http.StatusOK)
+	body.WriteTo(w)
+}
+
+// catalogFormat picks the file format from ?format, falling back to the given media
+// type header. It returns "" when neither names a supported format.
+func catalogFormat(r *http.Request, mediaType string) string {
+	switch r.URL.Query().Get("format") {
+	case catalog.FormatCSV:
+		return catalog.FormatCSV
+	case catalog.FormatJSONL:
+		return catalog.FormatJSONL
+	case "":
+	default:
+		return ""
+	}
+	switch {
+	case strings.Contains(mediaType, "csv"):
+		return catalog.FormatCSV
+	case strings.Contains(mediaType, "ndjson"), strings.Contains(mediaType, "jsonl"), strings.Contains(mediaType, "json-lines"):
+		return catalog.FormatJSONL
+	}
+	return ""
+}
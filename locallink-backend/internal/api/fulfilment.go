@@ -0,0 +1,251 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/LocalLink/internal/auth"
+	"github.com/LocalLink/internal/database"
+	"github.com/LocalLink/internal/geojson"
+	"github.com/LocalLink/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Limits on a single delivery zone upload.
+const (
+	maxZones      = 50
+	maxZonesBytes = 5 << 20
+)
+
+// co2KgPerKm is the average emission of a small diesel delivery van per kilometre
+// (UK government conversion factors), used for food-miles estimates.
+const co2KgPerKm = 0.24
+
+const metersPerMile = 1609.344
+
+// Pickup Point and Delivery Option Handlers
+func (h *Handler) CreatePickupPoint(w http.ResponseWriter, r *http.Request) {
+	producerID, _ := auth.GetUserIDFromContext(r.Context())
+	var point models.PickupPoint
+	if err := json.NewDecoder(r.Body).Decode(&point); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	point.ProducerID = producerID
+	point.Name = strings.TrimSpace(point.Name)
+	point.Address = strings.TrimSpace(point.Address)
+	switch {
+	case point.Name == "":
+		respondWithError(w, http.StatusBadRequest, "Pickup point name is required")
+		return
+	case point.Address == "":
+		respondWithError(w, http.StatusBadRequest, "Pickup point address is required")
+		return
+	}
+	if msg := h.locate(r.Context(), point.Address, &point.Latitude, &point.Longitude); msg != "" {
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+	if !validCoordinates(point.Latitude, point.Longitude) {
+		respondWithError(w, http.StatusBadRequest, "latitude or longitude is out of range")
+		return
+	}
+	if msg := validateOpeningHours("openingWindows", point.OpeningWindows); msg != "" {
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+	if err := h.store.CreatePickupPoint(r.Context(), &point); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create pickup point")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, point)
+}
+
+func (h *Handler) GetPickupPoints(w http.ResponseWriter, r *http.Request) {
+	producerID, _ := strconv.Atoi(chi.URLParam(r, "producerID"))
+	points, err := h.store.GetPickupPointsByProducer(r.Context(), producerID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Could not fetch pickup points")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, points)
+}
+
+func (h *Handler) DeactivatePickupPoint(w http.ResponseWriter, r *http.Request) {
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+	pointID, _ := strconv.Atoi(chi.URLParam(r, "pointID"))
+	point, err := h.store.GetPickupPoint(r.Context(), pointID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Pickup point not found")
+		return
+	}
+	if point.ProducerID != userID {
+		respondWithError(w, http.StatusForbidden, "You are not authorized to modify this pickup point")
+		return
+	}
+	if err := h.store.DeactivatePickupPoint(r.Context(), pointID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to remove pickup point")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) CreateDeliveryOption(w http.ResponseWriter, r *http.Request) {
+	producerID, _ := auth.GetUserIDFromContext(r.Context())
+	var option models.DeliveryOption
+	if err := json.NewDecoder(r.Body).Decode(&option); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	option.ProducerID = producerID
+	option.Name = strings.TrimSpace(option.Name)
+	switch {
+	case option.Name == "":
+		respondWithError(w, http.StatusBadRequest, "Delivery option name is required")
+		return
+	case option.RadiusMeters != nil && *option.RadiusMeters <= 0:
+		respondWithError(w, http.StatusBadRequest, "radiusMeters must be positive")
+		return
+	case option.Fee < 0 || option.MinimumOrder < 0:
+		respondWithError(w, http.StatusBadRequest, "fee and minimumOrder cannot be negative")
+		return
+	}
+	// A radius is measured from the storefront, so one must exist first. Options
+	// without a radius deliver only to the zones uploaded for them.
+	if option.RadiusMeters != nil {
+		if _, err := h.store.GetProducerProfile(r.Context(), producerID); err != nil {
+			respondWithError(w, http.StatusConflict, "Set up your producer profile location before offering delivery")
+			return
+		}
+	}
+	if err := h.store.CreateDeliveryOption(r.Context(), &option); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create delivery option")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, option)
+}
+
+func (h *Handler) GetDeliveryOptions(w http.ResponseWriter, r *http.Request) {
+	producerID, _ := strconv.Atoi(chi.URLParam(r, "producerID"))
+	options, err := h.store.GetDeliveryOptionsByProducer(r.Context(), producerID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Could not fetch delivery options")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, options)
+}
+
+func (h *Handler) DeactivateDeliveryOption(w http.ResponseWriter, r *http.Request) {
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+	optionID, _ := strconv.Atoi(chi.URLParam(r, "optionID"))
+	option, err := h.store.GetDeliveryOption(r.Context(), optionID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Delivery option not found")
+		return
+	}
+	if option.ProducerID != userID {
+		respondWithError(w, http.StatusForbidden, "You are not authorized to modify this delivery option")
+		return
+	}
+	if err := h.store.DeactivateDeliveryOption(r.Context(), optionID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to remove delivery option")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UploadDeliveryZones replaces a delivery option's zones with the polygons in a GeoJSON
+// FeatureCollection. Each feature's properties may give a "name" and a "fee"; zones
+// without a fee charge the delivery option's.
+func (h *Handler) UploadDeliveryZones(w http.ResponseWriter, r *http.Request) {
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+	optionID, _ := strconv.Atoi(chi.URLParam(r, "optionID"))
+	option, err := h.store.GetDeliveryOption(r.Context(), optionID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Delivery option not found")
+		return
+	}
+	if option.ProducerID != userID {
+		respondWithError(w, http.StatusForbidden, "You are not authorized to modify this delivery option")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxZonesBytes)
+	var collection geojson.FeatureCollection
+	if err := json.NewDecoder(r.Body).Decode(&collection); err != nil || collection.Type != "FeatureCollection" {
+		respondWithError(w, http.StatusBadRequest, "Expected a GeoJSON FeatureCollection")
+		return
+	}
+	if len(collection.Features) > maxZones {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("A delivery option can have at most %d zones", maxZones))
+		return
+	}
+	zones := make([]models.DeliveryZone, len(collection.Features))
+	for i, feature := range collection.Features {
+		if err := geojson.ValidatePolygon(feature.Geometry); err != nil {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Feature %d: %v", i+1, err))
+			return
+		}
+		zone := models.DeliveryZone{Area: feature.Geometry}
+		zone.Name, _ = feature.Properties["name"].(string)
+		if raw, ok := feature.Properties["fee"]; ok && raw != nil {
+			fee, ok := raw.(float64)
+			if !ok || fee < 0 {
+				respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Feature %d: fee must be a non-negative number", i+1))
+				return
+			}
+			zone.Fee = &fee
+		}
+		zones[i] = zone
+	}
+
+	saved, err := h.store.ReplaceDeliveryZones(r.Context(), optionID, zones)
+	if err != nil {
+		if errors.Is(err, database.ErrInvalidZone) {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to save delivery zones")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, zonesToGeoJSON(saved))
+}
+
+func (h *Handler) GetDeliveryZones(w http.ResponseWriter, r *http.Request) {
+	optionID, _ := strconv.Atoi(chi.URLParam(r, "optionID"))
+	zones, err := h.store.GetDeliveryZones(r.Context(), optionID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Could not fetch delivery zones")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, zonesToGeoJSON(zones))
+}
+
+func zonesToGeoJSON(zones []models.DeliveryZone) geojson.FeatureCollection {
+	features := make([]geojson.Feature, len(zones))
+	for i, z := range zones {
+		props := map[string]interface{}{"name": z.Name}
+		if z.Fee != nil {
+			props["fee"] = *z.Fee
+		}
+		features[i] = geojson.NewFeature(z.ID, z.Area, props)
+	}
+	return geojson.NewFeatureCollection(features)
+}
+
+// foodMiles turns an order's producer-to-buyer distance into the estimate shown on the
+// order, rounded for display.
+func foodMiles(distanceMeters float64) *models.FoodMiles {
+	round := func(v float64) float64 { return math.Round(v*100) / 100 }
+	return &models.FoodMiles{
+		DistanceMeters: math.Round(distanceMeters),
+		Miles:          round(distanceMeters / metersPerMile),
+		CO2Kg:          round(distanceMeters / 1000 * co2KgPerKm),
+	}
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/LocalLink/internal/geocode"
+	"github.com/LocalLink/internal/models"
+)
+
+// Geocoding Handlers
+
+// Geocode resolves ?q, an address or postcode, to coordinates.
+func (h *Handler) Geocode(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		respondWithError(w, http.StatusBadRequest, "q is required")
+		return
+	}
+	result, err := h.geocoder.Geocode(r.Context(), query)
+	if err != nil {
+		respondWithGeocodeError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, result)
+}
+
+// ReverseGeocode names the nearest known place to ?lat and ?lon, for display.
+func (h *Handler) ReverseGeocode(w http.ResponseWriter, r *http.Request) {
+	lat, err1 := strconv.ParseFloat(r.URL.Query().Get("lat"), 64)
+	lon, err2 := strconv.ParseFloat(r.URL.Query().Get("lon"), 64)
+	if err1 != nil || err2 != nil || !validCoordinates(lat, lon) {
+		respondWithError(w, http.StatusBadRequest, "lat and lon must be valid coordinates")
+		return
+	}
+	result, err := h.geocoder.Reverse(r.Context(), lat, lon)
+	if err != nil {
+		respondWithGeocodeError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, result)
+}
+
+func respondWithGeocodeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, geocode.ErrNotFound):
+		respondWithError(w, http.StatusNotFound, "No location found for that address")
+	case errors.Is(err, geocode.ErrUnavailable):
+		respondWithError(w, http.StatusServiceUnavailable, "Address lookup is not available")
+	default:
+		respondWithError(w, http.StatusInternalServerError, "Address lookup failed")
+	}
+}
+
+// locate fills in coordinates from address when they were left out (both zero). It
+// returns a message for a 400 response if the address can't be resolved.
+func (h *Handler) locate(ctx context.Context, address string, lat, lon *float64) string {
+	if *lat != 0 || *lon != 0 || strings.TrimSpace(address) == "" {
+		return ""
+	}
+	result, err := h.geocoder.Geocode(ctx, address)
+	switch {
+	case errors.Is(err, geocode.ErrNotFound):
+		return fmt.Sprintf("Could not find a location for %q; give latitude and longitude instead", address)
+	case errors.Is(err, geocode.ErrUnavailable):
+		return "Address lookup is not available; give latitude and longitude instead"
+	case err != nil:
+		return "Address lookup failed"
+	}
+	*lat, *lon = result.Latitude, result.Longitude
+	return ""
+}
+
+// locateUpdate checks a product update's new location, geocoding its address when no
+// coordinates are given.
+func (h *Handler) locateUpdate(r *http.Request, input *models.UpdateProductInput) string {
+	if (input.Latitude == nil) != (input.Longitude == nil) {
+		return "latitude and longitude must be given together"
+	}
+	if input.Latitude == nil && input.Address != nil {
+		if strings.TrimSpace(*input.Address) == "" {
+			return "address must not be empty"
+		}
+		var lat, lon float64
+		if msg := h.locate(r.Context(), *input.Address, &lat, &lon); msg != "" {
+			return msg
+		}
+		input.Latitude, input.Longitude = &lat, &lon
+	}
+	if input.Latitude != nil && !validCoordinates(*input.Latitude, *input.Longitude) {
+		return "latitude or longitude is out of range"
+	}
+	return ""
+}
+
+func (h *Handler) locateDelivery(r *http.Request, input *models.CreateOrderInput) string {
+	if addr := input.DeliveryAddress; addr != nil {
+		return h.locate(r.Context(), addr.Address, &addr.Latitude, &addr.Longitude)
+	}
+	return ""
+}
@@ -11,7 +11,10 @@ import (
 	"github.com/LocalLink/internal/auth"
 	"github.com/LocalLink/internal/config"
 	"github.com/LocalLink/internal/database"
+	"github.com/LocalLink/internal/geocode"
 	"github.com/LocalLink/internal/models"
+	"github.com/LocalLink/internal/oidc"
+	"github.com/LocalLink/internal/storage"
 	"github.com/LocalLink/internal/websocket"
 
 	"github.com/go-chi/chi/v5"
@@ -20,13 +23,17 @@ import (
 )
 
 type Handler struct {
-	store *database.Store
-	cfg   *config.Config
-	hub   *websocket.Hub
+	store         *database.Store
+	cfg           *config.Config
+	keys          *auth.KeySet
+	hub           *websocket.Hub
+	blobs         storage.BlobStore
+	geocoder      geocode.Geocoder
+	oidcProviders map[string]*oidc.Provider
 }
 
-func NewHandler(store *database.Store, cfg *config.Config, hub *websocket.Hub) *Handler {
-	return &Handler{store: store, cfg: cfg, hub: hub}
+func NewHandler(store *database.Store, cfg *config.Config, keys *auth.KeySet, hub *websocket.Hub, blobs storage.BlobStore, geocoder geocode.Geocoder) *Handler {
+	return &Handler{store: store, cfg: cfg, keys: keys, hub: hub, blobs: blobs, geocoder: geocoder, oidcProviders: oidc.NewProviders(cfg)}
 }
 
 // WebSocket Handler
@@ -92,12 +99,29 @@ func (h *Handler) LoginUser(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusUnauthorized, "Invalid credentials")
 		return
 	}
-	token, err := auth.GenerateJWT(user.ID, h.cfg)
+	result, err := h.loginResult(r, user, input.Device)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to generate token")
 		return
 	}
-	respondWithJSON(w, http.StatusOK, map[string]string{"token": token})
+	respondWithJSON(w, http.StatusOK, result)
+}
+
+// loginResult starts a session for an authenticated user, or issues an MFA challenge
+// when the account has two-factor authentication enabled.
+func (h *Handler) loginResult(r *http.Request, user *models.User, device string) (map[string]interface{}, error) {
+	if user.MFAEnabled {
+		mfaToken, err := auth.GenerateMFAChallengeToken(user.ID, user.Role, h.keys)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"mfaRequired": true, "mfaToken": mfaToken}, nil
+	}
+	token, err := h.startSession(r, user, device)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"token": token}, nil
 }
 
 func (h *Handler) GetUserProfile(w http.ResponseWriter, r *http.Request) {
@@ -140,31 +164,97 @@ func (h *Handler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
-	var product models.Product
-	if err := json.NewDecoder(r.Body).Decode(&product); err != nil {
+	var input models.CreateProductInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
 		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
 		return
 	}
+	product := input.Product
 	product.ProducerID = producerID
+	if product.Unit == "" {
+		product.Unit = "each"
+	}
+	if product.QuantityStep == 0 {
+		product.QuantityStep = 1
+	}
+	if product.Status == "" {
+		product.Status = models.ProductStatusPublished
+	}
+	if !models.ProductStatuses[product.Status] || product.Status == models.ProductStatusArchived {
+		respondWithError(w, http.StatusBadRequest, "status must be draft, published or paused")
+		return
+	}
+	if msg := validateUnit(product.Unit, product.QuantityStep); msg != "" {
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+	if msg := validateMarkdowns(product.Markdowns); msg != "" {
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+	if msg := h.locate(r.Context(), input.Address, &product.Latitude, &product.Longitude); msg != "" {
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+	// 0,0 is how a left-out location arrives; no product is listed there.
+	if product.Latitude == 0 && product.Longitude == 0 {
+		respondWithError(w, http.StatusBadRequest, "latitude and longitude, or an address to look them up from, are required")
+		return
+	}
+	if !validCoordinates(product.Latitude, product.Longitude) {
+		respondWithError(w, http.StatusBadRequest, "latitude or longitude is out of range")
+		return
+	}
+	product.Category = normalizeCategory(product.Category)
+	for _, variant := range product.Variants {
+		if msg := validateVariant(variant.Name, variant.Price, variant.Quantity); msg != "" {
+			respondWithError(w, http.StatusBadRequest, msg)
+			return
+		}
+	}
 	if err := h.store.CreateProduct(r.Context(), &product); err != nil {
+		if database.IsUniqueViolation(err) {
+			respondWithError(w, http.StatusConflict, "You already have a product with this SKU")
+			return
+		}
 		respondWithError(w, http.StatusInternalServerError, "Failed to create product")
 		return
 	}
+	if product.Status == models.ProductStatusPublished {
+		h.notifySavedSearches(r.Context(), product.ID)
+	}
 	respondWithJSON(w, http.StatusCreated, product)
 }
 
 func (h *Handler) GetProductsNearby(w http.ResponseWriter, r *http.Request) {
-	lat, _ := strconv.ParseFloat(r.URL.Query().Get("lat"), 64)
-	lon, _ := strconv.ParseFloat(r.URL.Query().Get("lon"), 64)
-	radius, _ := strconv.Atoi(r.URL.Query().Get("radius"))
-	if radius == 0 {
-		radius = 5000 // default 5km
+	lat, lon, radius, ok := h.searchArea(w, r, 5000) // default 5km
+	if !ok {
+		return
+	}
+	// ?sort=distance|price|newest, nearest first by default.
+	sortBy := r.URL.Query().Get("sort")
+	if sortBy == "" {
+		sortBy = models.SortDistance
 	}
-	products, err := h.store.GetProductsNearby(r.Context(), lat, lon, radius)
+	if sortBy != models.SortDistance && sortBy != models.SortPrice && sortBy != models.SortNewest {
+		respondWithError(w, http.StatusBadRequest, "sort must be distance, price or newest")
+		return
+	}
+	products, err := h.store.GetProductsNearby(r.Context(), lat, lon, radius, sortBy)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to fetch products")
 		return
 	}
+	// ?reducedToClear=true keeps only perishables currently marked down.
+	if r.URL.Query().Get("reducedToClear") == "true" {
+		reduced := products[:0]
+		for _, p := range products {
+			if p.DiscountPercent > 0 {
+				reduced = append(reduced, p)
+			}
+		}
+		products = reduced
+	}
 	respondWithJSON(w, http.StatusOK, products)
 }
 
@@ -185,14 +275,64 @@ func (h *Handler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
 		return
 	}
+	if input.Status != nil && !models.ProductStatuses[*input.Status] {
+		respondWithError(w, http.StatusBadRequest, "status must be draft, published, paused or archived")
+		return
+	}
+	if product.Status == models.ProductStatusArchived && (input.Status == nil || *input.Status == models.ProductStatusArchived) {
+		respondWithError(w, http.StatusConflict, "Archived products cannot be modified; change the status to restore it first")
+		return
+	}
+	if input.Quantity != nil && product.BatchTracked {
+		respondWithError(w, http.StatusConflict, "Stock for this product is managed through its batches")
+		return
+	}
+	if input.Category != nil {
+		*input.Category = normalizeCategory(*input.Category)
+	}
+	if input.Markdowns != nil {
+		if *input.Markdowns == nil {
+			*input.Markdowns = []models.Markdown{}
+		}
+		if msg := validateMarkdowns(*input.Markdowns); msg != "" {
+			respondWithError(w, http.StatusBadRequest, msg)
+			return
+		}
+	}
+	if msg := h.locateUpdate(r, &input); msg != "" {
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+	if input.Unit != nil || input.QuantityStep != nil {
+		unit, step := product.Unit, product.QuantityStep
+		if input.Unit != nil {
+			unit = *input.Unit
+		}
+		if input.QuantityStep != nil {
+			step = *input.QuantityStep
+		}
+		if msg := validateUnit(unit, step); msg != "" {
+			respondWithError(w, http.StatusBadRequest, msg)
+			return
+		}
+	}
 	updatedProduct, err := h.store.UpdateProduct(r.Context(), productID, input)
 	if err != nil {
+		if database.IsUniqueViolation(err) {
+			respondWithError(w, http.StatusConflict, "You already have a product with this SKU")
+			return
+		}
 		respondWithError(w, http.StatusInternalServerError, "Failed to update product")
 		return
 	}
+	if product.Status != models.ProductStatusPublished && updatedProduct.Status == models.ProductStatusPublished {
+		h.notifySavedSearches(r.Context(), productID)
+	}
 	respondWithJSON(w, http.StatusOK, updatedProduct)
 }
 
+// DeleteProduct archives rather than deletes, so orders that reference the product
+// keep their product data.
 func (h *Handler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
 	userID, _ := auth.GetUserIDFromContext(r.Context())
 	productID, _ := strconv.Atoi(chi.URLParam(r, "productID"))
@@ -205,13 +345,31 @@ func (h *Handler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusForbidden, "You are not authorized to delete this product")
 		return
 	}
-	if err := h.store.DeleteProduct(r.Context(), productID); err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to delete product")
+	if err := h.store.ArchiveProduct(r.Context(), productID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to archive product")
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// GetPriceHistory lists every price the product and its variants have had. Only the
+// producer can see it.
+func (h *Handler) GetPriceHistory(w http.ResponseWriter, r *http.Request) {
+	product, ok := h.ownedProduct(w, r)
+	if !ok {
+		return
+	}
+	history, err := h.store.GetPriceHistory(r.Context(), product.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Could not fetch price history")
+		return
+	}
+	if history == nil {
+		history = []models.PriceChange{}
+	}
+	respondWithJSON(w, http.StatusOK, history)
+}
+
 // Order Handlers
 func (h *Handler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 	buyerID, _ := auth.GetUserIDFromContext(r.Context())
@@ -220,9 +378,24 @@ func (h *Handler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
 		return
 	}
+	if msg := h.locateDelivery(r, &input); msg != "" {
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
 	order, err := h.store.CreateOrder(r.Context(), input, buyerID)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to create order: %v", err))
+		var choiceErr *database.FulfilmentError
+		var itemErr *database.ItemError
+		switch {
+		case errors.As(err, &choiceErr):
+			respondWithError(w, http.StatusBadRequest, choiceErr.Error())
+		case errors.As(err, &itemErr):
+			respondWithError(w, http.StatusBadRequest, itemErr.Error())
+		case errors.Is(err, database.ErrSlotUnavailable), errors.Is(err, database.ErrInsufficientStock):
+			respondWithError(w, http.StatusConflict, err.Error())
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Failed to create order")
+		}
 		return
 	}
 	respondWithJSON(w, http.StatusCreated, order)
@@ -250,6 +423,13 @@ func (h *Handler) GetOrderDetails(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusForbidden, "You are not authorized to view this order")
 		return
 	}
+	// Food miles are an extra; the order is still shown without them.
+	distance, err := h.store.GetOrderDistance(r.Context(), orderID)
+	if err != nil {
+		log.Printf("food miles for order %d: %v", orderID, err)
+	} else if distance != nil {
+		order.FoodMiles = foodMiles(*distance)
+	}
 	respondWithJSON(w, http.StatusOK, order)
 }
 
@@ -271,6 +451,14 @@ func (h *Handler) UpdateOrderStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	updatedOrder, err := h.store.UpdateOrderStatus(r.Context(), orderID, input.Status)
+	if errors.Is(err, database.ErrPreorderWaiting) {
+		respondWithError(w, http.StatusConflict, "Pre-orders can only be cancelled until their harvest is released")
+		return
+	}
+	if errors.Is(err, database.ErrOrderCancelled) {
+		respondWithError(w, http.StatusConflict, "Cancelled orders cannot be reopened")
+		return
+	}
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to update order status")
 		return
@@ -310,6 +498,12 @@ func (h *Handler) GetProductReviews(w http.ResponseWriter, r *http.Request) {
 	respondWithJSON(w, http.StatusOK, reviews)
 }
 
+// GetJWKS publishes the public keys that verify our tokens.
+func (h *Handler) GetJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	respondWithJSON(w, http.StatusOK, h.keys.JWKS())
+}
+
 // JSON response helpers
 func respondWithError(w http.ResponseWriter, code int, message string) {
 	respondWithJSON(w, code, map[string]string{"error": message})
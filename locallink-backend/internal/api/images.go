@@ -0,0 +1,209 @@
+package api
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/LocalLink/internal/auth"
+	"github.com/LocalLink/internal/images"
+	"github.com/LocalLink/internal/models"
+	"github.com/LocalLink/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Product Image Handlers
+func (h *Handler) UploadProductImage(w http.ResponseWriter, r *http.Request) {
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+	productID, _ := strconv.Atoi(chi.URLParam(r, "productID"))
+	product, err := h.store.GetProductByID(r.Context(), productID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Product not found")
+		return
+	}
+	if product.ProducerID != userID {
+		respondWithError(w, http.StatusForbidden, "You are not authorized to modify this product")
+		return
+	}
+	count, err := h.store.CountProductImages(r.Context(), productID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	if count >= h.cfg.MaxImagesPerProduct {
+		respondWithError(w, http.StatusConflict, fmt.Sprintf("A product can have at most %d images", h.cfg.MaxImagesPerProduct))
+		return
+	}
+
+	// Allow a little room for the multipart envelope around the file itself.
+	r.Body = http.MaxBytesReader(w, r.Body, h.cfg.MaxImageUploadBytes+1<<20)
+	file, _, err := r.FormFile("image")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			respondWithError(w, http.StatusRequestEntityTooLarge, "Image is too large")
+			return
+		}
+		respondWithError(w, http.StatusBadRequest, "Expected a multipart form with an \"image\" file")
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, h.cfg.MaxImageUploadBytes+1))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Failed to read upload")
+		return
+	}
+	if int64(len(data)) > h.cfg.MaxImageUploadBytes {
+		respondWithError(w, http.StatusRequestEntityTooLarge, "Image is too large")
+		return
+	}
+
+	variants, err := images.Process(data)
+	if err != nil {
+		if errors.Is(err, images.ErrUnsupportedType) {
+			respondWithError(w, http.StatusUnsupportedMediaType, err.Error())
+			return
+		}
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	token, err := randomHex(12)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to store image")
+		return
+	}
+	img := models.ProductImage{
+		ProductID:     productID,
+		StoragePrefix: fmt.Sprintf("products/%d/%s", productID, token),
+		OriginalExt:   variants[0].Extension,
+		VariantExt:    variants[1].Extension,
+		Width:         variants[0].Width,
+		Height:        variants[0].Height,
+	}
+	for _, variant := range variants {
+		if err := h.blobs.Put(r.Context(), img.BlobKey(variant.Name), bytes.NewReader(variant.Data), variant.ContentType); err != nil {
+			h.deleteImageBlobs(r, img)
+			respondWithError(w, http.StatusInternalServerError, "Failed to store image")
+			return
+		}
+	}
+	if err := h.store.CreateProductImage(r.Context(), &img); err != nil {
+		h.deleteImageBlobs(r, img)
+		respondWithError(w, http.StatusInternalServerError, "Failed to save image")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, img)
+}
+
+func (h *Handler) GetProductImages(w http.ResponseWriter, r *http.Request) {
+	productID, _ := strconv.Atoi(chi.URLParam(r, "productID"))
+	imagesByProduct, err := h.store.GetImagesForProducts(r.Context(), []int{productID})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Could not fetch images")
+		return
+	}
+	productImages := imagesByProduct[productID]
+	if productImages == nil {
+		productImages = []models.ProductImage{}
+	}
+	respondWithJSON(w, http.StatusOK, productImages)
+}
+
+func (h *Handler) DeleteProductImage(w http.ResponseWriter, r *http.Request) {
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+	productID, _ := strconv.Atoi(chi.URLParam(r, "productID"))
+	imageID, _ := strconv.Atoi(chi.URLParam(r, "imageID"))
+	img, err := h.store.GetProductImage(r.Context(), imageID)
+	if err != nil || img.ProductID != productID {
+		respondWithError(w, http.StatusNotFound, "Image not found")
+		return
+	}
+	product, err := h.store.GetProductByID(r.Context(), productID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Product not found")
+		return
+	}
+	if product.ProducerID != userID {
+		respondWithError(w, http.StatusForbidden, "You are not authorized to modify this product")
+		return
+	}
+	if err := h.store.DeleteProductImage(r.Context(), imageID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete image")
+		return
+	}
+	h.deleteImageBlobs(r, *img)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ServeProductImage serves one rendition of an image. Renditions never change once
+// written, so they can be cached indefinitely.
+func (h *Handler) ServeProductImage(w http.ResponseWriter, r *http.Request) {
+	imageID, _ := strconv.Atoi(chi.URLParam(r, "imageID"))
+	size := chi.URLParam(r, "size")
+	if !isImageSize(size) {
+		respondWithError(w, http.StatusNotFound, "Image not found")
+		return
+	}
+	img, err := h.store.GetProductImage(r.Context(), imageID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Image not found")
+		return
+	}
+	blob, err := h.blobs.Get(r.Context(), img.BlobKey(size))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			respondWithError(w, http.StatusNotFound, "Image not found")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to read image")
+		return
+	}
+	defer blob.Close()
+
+	info := blob.Info()
+	w.Header().Set("Content-Type", info.ContentType)
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("ETag", fmt.Sprintf(`"%d-%s"`, img.ID, size))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, "", info.ModTime, blob)
+}
+
+func (h *Handler) deleteImageBlobs(r *http.Request, img models.ProductImage) {
+	keys := []string{img.BlobKey("original")}
+	for _, size := range images.Sizes {
+		keys = append(keys, img.BlobKey(size.Name))
+	}
+	for _, key := range keys {
+		if err := h.blobs.Delete(r.Context(), key); err != nil {
+			log.Printf("failed to delete blob %s: %v", key, err)
+		}
+	}
+}
+
+func isImageSize(size string) bool {
+	if size == "original" {
+		return true
+	}
+	for _, s := range images.Sizes {
+		if s.Name == size {
+			return true
+		}
+	}
+	return false
+}
+
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/LocalLink/internal/auth"
+	"github.com/LocalLink/internal/models"
+)
+
+// Saved Location Handlers
+
+// SetDefaultLocation saves the caller's default search location, from coordinates or
+// a geocoded address.
+func (h *Handler) SetDefaultLocation(w http.ResponseWriter, r *http.Request) {
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+	var loc models.Location
+	if err := json.NewDecoder(r.Body).Decode(&loc); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	loc.Address = strings.TrimSpace(loc.Address)
+	if loc.Latitude == 0 && loc.Longitude == 0 && loc.Address == "" {
+		respondWithError(w, http.StatusBadRequest, "Give latitude and longitude or an address")
+		return
+	}
+	if msg := h.locate(r.Context(), loc.Address, &loc.Latitude, &loc.Longitude); msg != "" {
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+	if !validCoordinates(loc.Latitude, loc.Longitude) {
+		respondWithError(w, http.StatusBadRequest, "latitude or longitude is out of range")
+		return
+	}
+	if loc.Label == "" {
+		loc.Label = loc.Address
+	}
+	if loc.Label == "" {
+		if place, err := h.geocoder.Reverse(r.Context(), loc.Latitude, loc.Longitude); err == nil {
+			loc.Label = place.Label
+		}
+	}
+	loc.Address = ""
+	if err := h.store.SetDefaultLocation(r.Context(), userID, &loc); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to save location")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, loc)
+}
+
+func (h *Handler) ClearDefaultLocation(w http.ResponseWriter, r *http.Request) {
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+	if err := h.store.SetDefaultLocation(r.Context(), userID, nil); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to clear location")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// searchArea reads ?lat, ?lon and ?radius for a nearby search, responding with 400 and
+// returning false if they are malformed or out of range. Signed-in users who leave
+// out the coordinates search around their saved default location.
+func (h *Handler) searchArea(w http.ResponseWriter, r *http.Request, defaultRadius int) (lat, lon float64, radius int, ok bool) {
+	q := r.URL.Query()
+	latParam, lonParam := q.Get("lat"), q.Get("lon")
+	switch {
+	case latParam == "" && lonParam == "":
+		userID, err := auth.GetUserIDFromContext(r.Context())
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "lat and lon are required")
+			return 0, 0, 0, false
+		}
+		user, err := h.store.GetUserByID(r.Context(), userID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Database error")
+			return 0, 0, 0, false
+		}
+		if user.DefaultLocation == nil {
+			respondWithError(w, http.StatusBadRequest, "lat and lon are required when no default location is saved")
+			return 0, 0, 0, false
+		}
+		lat, lon = user.DefaultLocation.Latitude, user.DefaultLocation.Longitude
+	default:
+		var err1, err2 error
+		lat, err1 = strconv.ParseFloat(latParam, 64)
+		lon, err2 = strconv.ParseFloat(lonParam, 64)
+		if err1 != nil || err2 != nil || !validCoordinates(lat, lon) {
+			respondWithError(w, http.StatusBadRequest, "lat and lon must be valid coordinates")
+			return 0, 0, 0, false
+		}
+	}
+
+	radius = min(defaultRadius, h.cfg.MaxSearchRadius)
+	if param := q.Get("radius"); param != "" {
+		var err error
+		if radius, err = strconv.Atoi(param); err != nil || radius <= 0 {
+			respondWithError(w, http.StatusBadRequest, "radius must be a positive whole number of metres")
+			return 0, 0, 0, false
+		}
+		if radius > h.cfg.MaxSearchRadius {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("radius cannot exceed %d metres", h.cfg.MaxSearchRadius))
+			return 0, 0, 0, false
+		}
+	}
+	return lat, lon, radius, true
+}
+
+func validCoordinates(lat, lon float64) bool {
+	return !math.IsNaN(lat) && !math.IsNaN(lon) && lat >= -90 && lat <= 90 && lon >= -180 && lon <= 180
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/LocalLink/internal/geojson"
+	"github.com/LocalLink/internal/models"
+)
+
+const (
+	// clusterBelowZoom is the first web-map zoom level at which products are shown
+	// individually rather than clustered.
+	clusterBelowZoom = 14
+	maxMapZoom       = 22
+	// clusterCellsPerTile sets the cluster grid: a 256px tile is split into this many
+	// cells per side, so clusters sit roughly 32px apart on screen.
+	clusterCellsPerTile = 8
+	// maxMapFeatures caps the individual products returned for one viewport.
+	maxMapFeatures = 2000
+)
+
+// Product Map Handlers
+
+// productMap is the GeoJSON FeatureCollection returned for a viewport. Truncated is
+// set when the viewport held more than maxMapFeatures products and only the first
+// maxMapFeatures were returned; clients should zoom in to see the rest.
+type productMap struct {
+	geojson.FeatureCollection
+	Truncated bool `json:"truncated"`
+}
+
+// GetProductMap returns the available products in ?bbox=minLon,minLat,maxLon,maxLat as
+// a GeoJSON FeatureCollection. Below clusterBelowZoom (?zoom) nearby products are
+// grouped into cluster features with a pointCount; a product alone in its cell is
+// returned as an ordinary product feature.
+func (h *Handler) GetProductMap(w http.ResponseWriter, r *http.Request) {
+	box, err := parseBoundingBox(r.URL.Query().Get("bbox"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	zoom, err := strconv.Atoi(r.URL.Query().Get("zoom"))
+	if err != nil || zoom < 0 || zoom > maxMapZoom {
+		respondWithError(w, http.StatusBadRequest, "zoom must be a whole number from 0 to 22")
+		return
+	}
+
+	var features []geojson.Feature
+	truncated := false
+	if zoom < clusterBelowZoom {
+		cell := 360 / math.Exp2(float64(zoom)) / clusterCellsPerTile
+		clusters, err := h.store.GetProductClusters(r.Context(), box, cell)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to fetch products")
+			return
+		}
+		for _, c := range clusters {
+			if c.Product != nil {
+				features = append(features, productFeature(*c.Product))
+				continue
+			}
+			features = append(features, geojson.NewFeature(nil, geojson.Point(c.Longitude, c.Latitude), map[string]interface{}{
+				"cluster":    true,
+				"pointCount": c.Count,
+				"minPrice":   c.MinPrice,
+			}))
+		}
+	} else {
+		// One extra row tells us whether the viewport held more than the cap.
+		products, err := h.store.GetProductsInBox(r.Context(), box, maxMapFeatures+1)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to fetch products")
+			return
+		}
+		if len(products) > maxMapFeatures {
+			products = products[:maxMapFeatures]
+			truncated = true
+		}
+		for _, p := range products {
+			features = append(features, productFeature(p))
+		}
+	}
+	respondWithJSON(w, http.StatusOK, productMap{FeatureCollection: geojson.NewFeatureCollection(features), Truncated: truncated})
+}
+
+// productFeature renders one product as a map point.
+func productFeature(p models.Product) geojson.Feature {
+	props := map[string]interface{}{
+		"cluster":    false,
+		"name":       p.Name,
+		"producerId": p.ProducerID,
+		"price":      p.Price,
+		"unit":       p.Unit,
+		"preorder":   p.Preorder,
+	}
+	if p.SalePrice != nil {
+		props["salePrice"] = *p.SalePrice
+	}
+	return geojson.NewFeature(p.ID, geojson.Point(p.Longitude, p.Latitude), props)
+}
+
+// parseBoundingBox parses minLon,minLat,maxLon,maxLat. Boxes crossing the antimeridian
+// must be requested as two boxes.
+func parseBoundingBox(raw string) (models.BoundingBox, error) {
+	var box models.BoundingBox
+	parts := strings.Split(raw, ",")
+	if len(parts) != 4 {
+		return box, errors.New("bbox must be minLon,minLat,maxLon,maxLat")
+	}
+	values := make([]float64, 4)
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || math.IsNaN(v) {
+			return box, errors.New("bbox must be minLon,minLat,maxLon,maxLat")
+		}
+		values[i] = v
+	}
+	box = models.BoundingBox{MinLon: values[0], MinLat: values[1], MaxLon: values[2], MaxLat: values[3]}
+	switch {
+	case box.MinLon < -180 || box.MaxLon > 180 || box.MinLat < -90 || box.MaxLat > 90:
+		return box, errors.New("bbox is out of range")
+	case box.MinLon >= box.MaxLon || box.MinLat >= box.MaxLat:
+		return box, errors.New("bbox minimums must be below its maximums")
+	}
+	return box, nil
+}
@@ -0,0 +1,249 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/LocalLink/internal/auth"
+	"github.com/LocalLink/internal/models"
+)
+
+// After maxMFAAttempts wrong codes in a row, MFA codes for the account are refused
+// for mfaLockout.
+const (
+	maxMFAAttempts = 5
+	mfaLockout     = 15 * time.Minute
+)
+
+// MFA Handlers
+func (h *Handler) EnrollMFA(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	user, err := h.store.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	if user.Role != "producer" {
+		respondWithError(w, http.StatusForbidden, "Two-factor authentication is only available for producer accounts")
+		return
+	}
+	if user.MFAEnabled {
+		respondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled")
+		return
+	}
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to generate secret")
+		return
+	}
+	if err := h.store.SetTOTPSecret(r.Context(), userID, secret); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to save secret")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]string{
+		"secret":     secret,
+		"otpauthUri": auth.TOTPURI(user.Email, secret),
+	})
+}
+
+func (h *Handler) ConfirmMFA(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	var input models.MFACodeInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	user, err := h.store.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	if user.MFAEnabled {
+		respondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled")
+		return
+	}
+	if user.TOTPSecret == "" {
+		respondWithError(w, http.StatusBadRequest, "Start enrolment before confirming")
+		return
+	}
+	ok, err := h.checkTOTP(r.Context(), user, input.Code)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Invalid code")
+		return
+	}
+	codes, hashes, err := auth.GenerateBackupCodes()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to generate backup codes")
+		return
+	}
+	if err := h.store.EnableMFA(r.Context(), userID, hashes); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to enable two-factor authentication")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string][]string{"backupCodes": codes})
+}
+
+func (h *Handler) RegenerateBackupCodes(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	var input models.MFACodeInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	user, err := h.store.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	if !user.MFAEnabled {
+		respondWithError(w, http.StatusUnauthorized, "Invalid code")
+		return
+	}
+	if !h.limitMFAAttempts(r.Context(), w, user.ID, func() (bool, error) { return h.checkTOTP(r.Context(), user, input.Code) }) {
+		return
+	}
+	codes, hashes, err := auth.GenerateBackupCodes()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to generate backup codes")
+		return
+	}
+	if err := h.store.RegenerateBackupCodes(r.Context(), userID, hashes); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to save backup codes")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string][]string{"backupCodes": codes})
+}
+
+func (h *Handler) DisableMFA(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	var input models.MFACodeInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	user, err := h.store.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	if !h.limitMFAAttempts(r.Context(), w, user.ID, func() (bool, error) { return h.verifyMFACode(r.Context(), user, input.Code) }) {
+		return
+	}
+	if err := h.store.DisableMFA(r.Context(), userID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to disable two-factor authentication")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// VerifyMFALogin exchanges the challenge token from LoginUser and a valid code for a session token.
+func (h *Handler) VerifyMFALogin(w http.ResponseWriter, r *http.Request) {
+	var input models.VerifyMFAInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	claims, err := auth.ParseMFAChallengeToken(input.MFAToken, h.keys)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired MFA token")
+		return
+	}
+	user, err := h.store.GetUserByID(r.Context(), claims.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid credentials")
+		return
+	}
+	if !h.limitMFAAttempts(r.Context(), w, user.ID, func() (bool, error) { return h.verifyMFACode(r.Context(), user, input.Code) }) {
+		return
+	}
+	fresh, err := h.store.SpendMFAChallenge(r.Context(), claims.ID, claims.ExpiresAt.Time)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	if !fresh {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired MFA token")
+		return
+	}
+	token, err := h.startSession(r, user, input.Device)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to generate token")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]string{"token": token})
+}
+
+// limitMFAAttempts runs check unless the account is locked out, counting invalid codes
+// towards the lockout. It writes the error response and returns false when the code
+// was not accepted.
+func (h *Handler) limitMFAAttempts(ctx context.Context, w http.ResponseWriter, userID int, check func() (bool, error)) bool {
+	locked, err := h.store.MFALocked(ctx, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Database error")
+		return false
+	}
+	if locked {
+		respondWithError(w, http.StatusTooManyRequests, "Too many invalid codes; try again later")
+		return false
+	}
+	ok, err := check()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Database error")
+		return false
+	}
+	if !ok {
+		if err := h.store.RecordMFAFailure(ctx, userID, maxMFAAttempts, mfaLockout); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Database error")
+			return false
+		}
+		respondWithError(w, http.StatusUnauthorized, "Invalid code")
+		return false
+	}
+	if err := h.store.ResetMFAFailures(ctx, userID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Database error")
+		return false
+	}
+	return true
+}
+
+// verifyMFACode accepts either a current TOTP code or an unused backup code.
+func (h *Handler) verifyMFACode(ctx context.Context, user *models.User, code string) (bool, error) {
+	if !user.MFAEnabled {
+		return false, nil
+	}
+	if step, ok := auth.ValidateTOTP(user.TOTPSecret, code, time.Now()); ok {
+		return h.store.UseTOTPStep(ctx, user.ID, step)
+	}
+	return h.store.ConsumeBackupCode(ctx, user.ID, auth.HashBackupCode(code))
+}
+
+// checkTOTP validates a TOTP code and spends its time step, so each code works once.
+func (h *Handler) checkTOTP(ctx context.Context, user *models.User, code string) (bool, error) {
+	step, ok := auth.ValidateTOTP(user.TOTPSecret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+	return h.store.UseTOTPStep(ctx, user.ID, step)
+}
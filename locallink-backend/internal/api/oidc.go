@@ -0,0 +1,153 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/LocalLink/internal/auth"
+	"github.com/LocalLink/internal/models"
+	"github.com/LocalLink/internal/oidc"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+const oidcStateCookie = "oidc_state"
+
+var errEmailNotVerified = errors.New("email not verified by provider")
+
+// OIDC Handlers
+func (h *Handler) StartOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	providerName := chi.URLParam(r, "provider")
+	provider, ok := h.oidcProviders[providerName]
+	if !ok {
+		respondWithError(w, http.StatusNotFound, "Unknown login provider")
+		return
+	}
+	state, errState := oidc.RandomString()
+	nonce, errNonce := oidc.RandomString()
+	verifier, errVerifier := oidc.RandomString()
+	if errState != nil || errNonce != nil || errVerifier != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start login")
+		return
+	}
+	authURL, err := provider.AuthCodeURL(r.Context(), state, nonce, verifier)
+	if err != nil {
+		log.Printf("oidc: %v", err)
+		respondWithError(w, http.StatusBadGateway, "Login provider unavailable")
+		return
+	}
+	stateToken, err := auth.GenerateOIDCStateToken(auth.OIDCStateClaims{
+		Provider: providerName,
+		State:    state,
+		Nonce:    nonce,
+		Verifier: verifier,
+	}, h.keys)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start login")
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    stateToken,
+		Path:     "/auth/oidc/" + providerName,
+		MaxAge:   600,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+func (h *Handler) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	providerName := chi.URLParam(r, "provider")
+	provider, ok := h.oidcProviders[providerName]
+	if !ok {
+		respondWithError(w, http.StatusNotFound, "Unknown login provider")
+		return
+	}
+	if providerError := r.URL.Query().Get("error"); providerError != "" {
+		respondWithError(w, http.StatusUnauthorized, "Login was not completed: "+providerError)
+		return
+	}
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Login session expired, please try again")
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Path: "/auth/oidc/" + providerName, MaxAge: -1})
+	state, err := auth.ParseOIDCStateToken(cookie.Value, h.keys)
+	if err != nil || state.Provider != providerName || state.State != r.URL.Query().Get("state") {
+		respondWithError(w, http.StatusBadRequest, "Invalid login state")
+		return
+	}
+
+	identity, err := provider.Exchange(r.Context(), r.URL.Query().Get("code"), state.Verifier, state.Nonce)
+	if err != nil {
+		log.Printf("oidc: %v", err)
+		respondWithError(w, http.StatusUnauthorized, "Login with provider failed")
+		return
+	}
+	user, err := h.findOrCreateOIDCUser(r.Context(), providerName, identity)
+	if err != nil {
+		if errors.Is(err, errEmailNotVerified) {
+			respondWithError(w, http.StatusForbidden, "Your provider has not verified this email address")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to sign in")
+		return
+	}
+
+	result, err := h.loginResult(r, user, "")
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to generate token")
+		return
+	}
+	if h.cfg.OIDCSuccessRedirectURL == "" {
+		respondWithJSON(w, http.StatusOK, result)
+		return
+	}
+	fragment := url.Values{}
+	for key, value := range result {
+		if s, ok := value.(string); ok {
+			fragment.Set(key, s)
+		}
+	}
+	http.Redirect(w, r, h.cfg.OIDCSuccessRedirectURL+"#"+fragment.Encode(), http.StatusFound)
+}
+
+// findOrCreateOIDCUser resolves an external identity to a local user. Unknown identities
+// are linked to an existing account, or a new buyer account, only by a verified email.
+func (h *Handler) findOrCreateOIDCUser(ctx context.Context, providerName string, identity *oidc.IdentityClaims) (*models.User, error) {
+	user, err := h.store.GetUserByIdentity(ctx, providerName, identity.Subject)
+	if err == nil {
+		return user, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	if !identity.EmailVerified || identity.Email == "" {
+		return nil, errEmailNotVerified
+	}
+
+	email := identity.Email
+	user, err = h.store.GetUserByEmail(ctx, email)
+	if errors.Is(err, pgx.ErrNoRows) {
+		name := identity.Name
+		if name == "" {
+			name = email
+		}
+		user = &models.User{Name: name, Email: email, Role: "buyer"}
+		err = h.store.CreateUser(ctx, user)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := h.store.LinkIdentity(ctx, user.ID, providerName, identity.Subject, email); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/LocalLink/internal/models"
+)
+
+// Pre-order Handlers
+
+// EnablePreorder sells a product ahead of its harvest, up to the given limit.
+func (h *Handler) EnablePreorder(w http.ResponseWriter, r *http.Request) {
+	product, ok := h.ownedProduct(w, r)
+	if !ok {
+		return
+	}
+	if len(product.Variants) > 0 || product.BatchTracked {
+		respondWithError(w, http.StatusConflict, "Pre-orders are not available for products with variants or batches")
+		return
+	}
+	var input models.PreorderInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	availableFrom, err := time.Parse(dateLayout, input.AvailableFrom)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "availableFrom must be a date in YYYY-MM-DD format")
+		return
+	}
+	if !availableFrom.After(time.Now()) {
+		respondWithError(w, http.StatusBadRequest, "availableFrom must be in the future")
+		return
+	}
+	if input.Limit <= 0 {
+		respondWithError(w, http.StatusBadRequest, "limit must be positive")
+		return
+	}
+	if input.Limit < product.PreorderedQuantity {
+		respondWithError(w, http.StatusConflict, "limit cannot be below the quantity already pre-ordered")
+		return
+	}
+	updated, err := h.store.EnablePreorder(r.Context(), product.ID, availableFrom, input.Limit)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to enable pre-orders")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, updated)
+}
+
+// ReleaseHarvest marks a pre-order product as harvested and adds the harvest to
+// stock. Waiting pre-orders are converted shortly after by the pre-order release job.
+func (h *Handler) ReleaseHarvest(w http.ResponseWriter, r *http.Request) {
+	product, ok := h.ownedProduct(w, r)
+	if !ok {
+		return
+	}
+	if !product.Preorder {
+		respondWithError(w, http.StatusConflict, "This product is not awaiting a harvest")
+		return
+	}
+	var input models.ReleaseHarvestInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if input.Quantity < 0 {
+		respondWithError(w, http.StatusBadRequest, "quantity cannot be negative")
+		return
+	}
+	updated, err := h.store.ReleaseHarvest(r.Context(), product.ID, input.Quantity)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to release harvest")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, updated)
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LocalLink/internal/auth"
+	"github.com/LocalLink/internal/models"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+// Producer Profile Handlers
+
+// UpdateProducerProfile creates or replaces the caller's storefront profile.
+func (h *Handler) UpdateProducerProfile(w http.ResponseWriter, r *http.Request) {
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+	user, err := h.store.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	if user.Role != "producer" {
+		respondWithError(w, http.StatusForbidden, "Only producers can have a storefront profile")
+		return
+	}
+	var profile models.ProducerProfile
+	if err := json.NewDecoder(r.Body).Decode(&profile); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	profile.ProducerID = userID
+	profile.FarmName = strings.TrimSpace(profile.FarmName)
+	if msg := h.locate(r.Context(), profile.PickupAddress, &profile.Latitude, &profile.Longitude); msg != "" {
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+	if msg := validateProducerProfile(&profile); msg != "" {
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+	saved, err := h.store.UpsertProducerProfile(r.Context(), &profile)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to save producer profile")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, saved)
+}
+
+// GetProducer returns a producer's storefront: profile, published products and
+// rating summary.
+func (h *Handler) GetProducer(w http.ResponseWriter, r *http.Request) {
+	producerID, _ := strconv.Atoi(chi.URLParam(r, "producerID"))
+	profile, err := h.store.GetProducerProfile(r.Context(), producerID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, "Producer not found")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Could not fetch producer")
+		return
+	}
+	storefront := models.ProducerStorefront{ProducerProfile: *profile}
+	if storefront.Products, err = h.store.GetPublishedProductsByProducer(r.Context(), producerID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Could not fetch producer products")
+		return
+	}
+	if storefront.Rating, err = h.store.GetProducerRating(r.Context(), producerID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Could not fetch producer rating")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, storefront)
+}
+
+func (h *Handler) GetProducersNearby(w http.ResponseWriter, r *http.Request) {
+	lat, lon, radius, ok := h.searchArea(w, r, 10000) // default 10km
+	if !ok {
+		return
+	}
+	producers, err := h.store.GetProducersNearby(r.Context(), lat, lon, radius)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to fetch producers")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, producers)
+}
+
+func validateProducerProfile(p *models.ProducerProfile) string {
+	if p.FarmName == "" {
+		return "farmName is required"
+	}
+	// 0,0 is how a left-out location arrives; it is never a real storefront.
+	if p.Latitude == 0 && p.Longitude == 0 {
+		return "latitude and longitude, or a pickupAddress to look them up from, are required"
+	}
+	if !validCoordinates(p.Latitude, p.Longitude) {
+		return "latitude or longitude is out of range"
+	}
+	return validateOpeningHours("openingHours", p.OpeningHours)
+}
+
+func validateOpeningHours(field string, hours []models.OpeningHours) string {
+	for _, h := range hours {
+		if h.Day < 0 || h.Day > 6 {
+			return field + " day must be from 0 (Sunday) to 6 (Saturday)"
+		}
+		opens, err1 := time.Parse("15:04", h.Opens)
+		closes, err2 := time.Parse("15:04", h.Closes)
+		if err1 != nil || err2 != nil {
+			return field + " times must be HH:MM"
+		}
+		if !closes.After(opens) {
+			return fmt.Sprintf("%s for day %d close before they open", field, h.Day)
+		}
+	}
+	return ""
+}
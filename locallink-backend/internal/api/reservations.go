@@ -0,0 +1,96 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/LocalLink/internal/auth"
+	"github.com/LocalLink/internal/database"
+	"github.com/LocalLink/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Stock Reservation Handlers
+
+// CreateReservation starts checkout by holding the basket's stock for the configured
+// reservation period.
+func (h *Handler) CreateReservation(w http.ResponseWriter, r *http.Request) {
+	buyerID, _ := auth.GetUserIDFromContext(r.Context())
+	var input models.CreateOrderInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if msg := h.locateDelivery(r, &input); msg != "" {
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+	reservation, err := h.store.CreateReservation(r.Context(), input, buyerID, h.cfg.ReservationTTL)
+	if err != nil {
+		var choiceErr *database.FulfilmentError
+		var itemErr *database.ItemError
+		switch {
+		case errors.As(err, &choiceErr):
+			respondWithError(w, http.StatusBadRequest, choiceErr.Error())
+		case errors.As(err, &itemErr):
+			respondWithError(w, http.StatusBadRequest, itemErr.Error())
+		case errors.Is(err, database.ErrSlotUnavailable), errors.Is(err, database.ErrInsufficientStock):
+			respondWithError(w, http.StatusConflict, err.Error())
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Failed to reserve stock")
+		}
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, reservation)
+}
+
+func (h *Handler) GetReservation(w http.ResponseWriter, r *http.Request) {
+	reservation, ok := h.buyerReservation(w, r)
+	if !ok {
+		return
+	}
+	respondWithJSON(w, http.StatusOK, reservation)
+}
+
+func (h *Handler) ConfirmReservation(w http.ResponseWriter, r *http.Request) {
+	reservation, ok := h.buyerReservation(w, r)
+	if !ok {
+		return
+	}
+	order, err := h.store.ConfirmReservation(r.Context(), reservation.ID)
+	if err != nil {
+		if errors.Is(err, database.ErrReservationInactive) {
+			respondWithError(w, http.StatusConflict, "Reservation has expired or was already used; start checkout again")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to create order")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, order)
+}
+
+func (h *Handler) ReleaseReservation(w http.ResponseWriter, r *http.Request) {
+	reservation, ok := h.buyerReservation(w, r)
+	if !ok {
+		return
+	}
+	if _, err := h.store.ReleaseReservation(r.Context(), reservation.ID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to release reservation")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) buyerReservation(w http.ResponseWriter, r *http.Request) (*models.Reservation, bool) {
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+	reservationID, _ := strconv.Atoi(chi.URLParam(r, "reservationID"))
+	reservation, err := h.store.GetReservation(r.Context(), reservationID)
+	if err != nil || reservation.BuyerID != userID {
+		respondWithError(w, http.StatusNotFound, "Reservation not found")
+		return nil, false
+	}
+	return reservation, true
+}
@@ -6,6 +6,8 @@ import (
 	"github.com/LocalLink/internal/auth"
 	"github.com/LocalLink/internal/config"
 	"github.com/LocalLink/internal/database"
+	"github.com/LocalLink/internal/geocode"
+	"github.com/LocalLink/internal/storage"
 	"github.com/LocalLink/internal/websocket"
 
 	"github.com/go-chi/chi/v5"
@@ -13,16 +15,16 @@ import (
 	"github.com/rs/cors" // <-- IMPORT THE CORS LIBRARY
 )
 
-func NewRouter(store *database.Store, cfg *config.Config, hub *websocket.Hub) *chi.Mux {
+func NewRouter(store *database.Store, cfg *config.Config, keys *auth.KeySet, hub *websocket.Hub, blobs storage.BlobStore, geocoder geocode.Geocoder) *chi.Mux {
 	r := chi.NewRouter()
-	h := NewHandler(store, cfg, hub)
+	h := NewHandler(store, cfg, keys, hub, blobs, geocoder)
 
 	// --- NEW: CORS Configuration ---
 	// This sets up the rules for which frontend origins are allowed to connect.
 	corsHandler := cors.New(cors.Options{
 		AllowedOrigins:   []string{"http://localhost:5173"}, // <-- YOUR REACT APP's URL
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-API-Key"},
 		ExposedHeaders:   []string{"Link"},
 		AllowCredentials: true,
 		MaxAge:           300, // Maximum value not ignored by any major browsers
@@ -34,14 +36,32 @@ func NewRouter(store *database.Store, cfg *config.Config, hub *websocket.Hub) *c
 	r.Use(middleware.Recoverer)
 
 	// --- Public Routes ---
+	r.Get("/.well-known/jwks.json", h.GetJWKS)
 	r.Post("/register", h.RegisterUser)
 	r.Post("/login", h.LoginUser)
-	r.Get("/products/nearby", h.GetProductsNearby)
+	r.Post("/login/mfa", h.VerifyMFALogin)
+	r.Get("/auth/oidc/{provider}/login", h.StartOIDCLogin)
+	r.Get("/auth/oidc/{provider}/callback", h.OIDCCallback)
+	r.With(auth.OptionalAuthMiddleware(keys, store)).Get("/products/nearby", h.GetProductsNearby)
+	r.Get("/products/map", h.GetProductMap)
+	r.Get("/geocode", h.Geocode)
+	r.Get("/geocode/reverse", h.ReverseGeocode)
 	r.Get("/products/{productID}/reviews", h.GetProductReviews)
+	r.Get("/products/{productID}/images", h.GetProductImages)
+	r.Get("/images/{imageID}/{size}", h.ServeProductImage)
+	r.With(auth.OptionalAuthMiddleware(keys, store)).Get("/producers/nearby", h.GetProducersNearby)
+	r.Get("/producers/{producerID}", h.GetProducer)
+	r.Get("/producers/{producerID}/pickup-points", h.GetPickupPoints)
+	r.Get("/producers/{producerID}/delivery-options", h.GetDeliveryOptions)
+	r.Get("/pickup-points/{pointID}/slots", h.GetPickupPointSlots)
+	r.Get("/delivery-options/{optionID}/slots", h.GetDeliveryOptionSlots)
+	r.Get("/delivery-options/{optionID}/zones", h.GetDeliveryZones)
+	r.Get("/subscription-plans", h.GetSubscriptionPlans)
+	r.Get("/subscription-plans/{planID}", h.GetSubscriptionPlan)
 
 	// --- Protected Routes ---
 	r.Group(func(r chi.Router) {
-		r.Use(auth.AuthMiddleware(cfg))
+		r.Use(auth.AuthMiddleware(keys, store))
 
 		// WebSocket connection
 		r.Get("/ws", func(w http.ResponseWriter, r *http.Request) {
@@ -51,20 +71,85 @@ func NewRouter(store *database.Store, cfg *config.Config, hub *websocket.Hub) *c
 		// User & Profile Management
 		r.Get("/users/me", h.GetUserProfile)
 		r.Put("/users/me", h.UpdateUserProfile)
+		r.Delete("/users/me", h.DeleteUserAccount)
+		r.Post("/users/me/deletion/cancel", h.CancelAccountDeletion)
+		r.Get("/users/me/export", h.ExportUserData)
+		r.Get("/users/me/sessions", h.GetSessions)
+		r.Delete("/users/me/sessions", h.RevokeOtherSessions)
+		r.Delete("/users/me/sessions/{sessionID}", h.RevokeSession)
+		r.Put("/users/me/producer-profile", h.UpdateProducerProfile)
+		r.Put("/users/me/location", h.SetDefaultLocation)
+		r.Delete("/users/me/location", h.ClearDefaultLocation)
+		r.Post("/users/me/saved-searches", h.CreateSavedSearch)
+		r.Get("/users/me/saved-searches", h.GetSavedSearches)
+		r.Delete("/users/me/saved-searches/{searchID}", h.DeleteSavedSearch)
+		r.Post("/users/me/mfa/enroll", h.EnrollMFA)
+		r.Post("/users/me/mfa/confirm", h.ConfirmMFA)
+		r.Post("/users/me/mfa/backup-codes", h.RegenerateBackupCodes)
+		r.Delete("/users/me/mfa", h.DisableMFA)
 
-		// Product Management
-		r.Post("/products", h.CreateProduct)
-		r.Put("/products/{productID}", h.UpdateProduct)
-		r.Delete("/products/{productID}", h.DeleteProduct)
+		// API Key Management
+		r.Post("/users/me/api-keys", h.CreateAPIKey)
+		r.Get("/users/me/api-keys", h.GetAPIKeys)
+		r.Delete("/users/me/api-keys/{keyID}", h.RevokeAPIKey)
 
 		// Order Management
 		r.Post("/orders", h.CreateOrder)
-		r.Get("/orders", h.GetUserOrders)
-		r.Get("/orders/{orderID}", h.GetOrderDetails)
-		r.Put("/orders/{orderID}/status", h.UpdateOrderStatus)
+		r.Post("/reservations", h.CreateReservation)
+		r.Get("/reservations/{reservationID}", h.GetReservation)
+		r.Post("/reservations/{reservationID}/confirm", h.ConfirmReservation)
+		r.Delete("/reservations/{reservationID}", h.ReleaseReservation)
+
+		// Pickup & Delivery
+		r.Post("/pickup-points", h.CreatePickupPoint)
+		r.Delete("/pickup-points/{pointID}", h.DeactivatePickupPoint)
+		r.Post("/delivery-options", h.CreateDeliveryOption)
+		r.Delete("/delivery-options/{optionID}", h.DeactivateDeliveryOption)
+		r.Put("/delivery-options/{optionID}/zones", h.UploadDeliveryZones)
+		r.Post("/time-slots", h.CreateTimeSlot)
+		r.Delete("/time-slots/{slotID}", h.DeleteTimeSlot)
 
 		// Review Management
 		r.Post("/products/{productID}/reviews", h.CreateReview)
+
+		// Subscriptions
+		r.Post("/subscription-plans", h.CreateSubscriptionPlan)
+		r.Put("/subscription-plans/{planID}", h.UpdateSubscriptionPlan)
+		r.Delete("/subscription-plans/{planID}", h.DeactivateSubscriptionPlan)
+		r.Post("/subscription-plans/{planID}/subscribe", h.Subscribe)
+		r.Get("/users/me/subscriptions", h.GetMySubscriptions)
+		r.Post("/subscriptions/{subscriptionID}/pause", h.PauseSubscription)
+		r.Post("/subscriptions/{subscriptionID}/resume", h.ResumeSubscription)
+		r.Post("/subscriptions/{subscriptionID}/skip", h.SkipSubscription)
+		r.Delete("/subscriptions/{subscriptionID}", h.CancelSubscription)
+	})
+
+	// --- Integration Routes (user token or scoped API key) ---
+	r.Group(func(r chi.Router) {
+		r.Use(auth.APIKeyMiddleware(store, keys, store))
+
+		// Product Management
+		r.With(auth.RequireScope(auth.ScopeProductsWrite)).Post("/products", h.CreateProduct)
+		r.With(auth.RequireScope(auth.ScopeProductsWrite)).Post("/products/import", h.ImportProducts)
+		r.With(auth.RequireScope(auth.ScopeProductsRead)).Get("/products/export", h.ExportProducts)
+		r.With(auth.RequireScope(auth.ScopeProductsWrite)).Put("/products/{productID}", h.UpdateProduct)
+		r.With(auth.RequireScope(auth.ScopeProductsWrite)).Delete("/products/{productID}", h.DeleteProduct)
+		r.With(auth.RequireScope(auth.ScopeProductsWrite)).Post("/products/{productID}/images", h.UploadProductImage)
+		r.With(auth.RequireScope(auth.ScopeProductsWrite)).Delete("/products/{productID}/images/{imageID}", h.DeleteProductImage)
+		r.With(auth.RequireScope(auth.ScopeProductsWrite)).Post("/products/{productID}/variants", h.CreateProductVariant)
+		r.With(auth.RequireScope(auth.ScopeProductsWrite)).Put("/products/{productID}/variants/{variantID}", h.UpdateProductVariant)
+		r.With(auth.RequireScope(auth.ScopeProductsWrite)).Delete("/products/{productID}/variants/{variantID}", h.DeleteProductVariant)
+		r.With(auth.RequireScope(auth.ScopeProductsWrite)).Post("/products/{productID}/batches", h.CreateProductBatch)
+		r.With(auth.RequireScope(auth.ScopeProductsWrite)).Delete("/products/{productID}/batches/{batchID}", h.DeleteProductBatch)
+		r.With(auth.RequireScope(auth.ScopeProductsWrite)).Put("/products/{productID}/preorder", h.EnablePreorder)
+		r.With(auth.RequireScope(auth.ScopeProductsWrite)).Post("/products/{productID}/harvest", h.ReleaseHarvest)
+		r.With(auth.RequireScope(auth.ScopeProductsRead)).Get("/products/{productID}/price-history", h.GetPriceHistory)
+
+		// Order Management
+		r.With(auth.RequireScope(auth.ScopeOrdersRead)).Get("/orders", h.GetUserOrders)
+		r.With(auth.RequireScope(auth.ScopeOrdersRead)).Get("/orders/{orderID}", h.GetOrderDetails)
+		r.With(auth.RequireScope(auth.ScopeOrdersWrite)).Put("/orders/{orderID}/status", h.UpdateOrderStatus)
+		r.With(auth.RequireScope(auth.ScopeOrdersWrite)).Post("/orders/{orderID}/reschedule", h.RescheduleOrder)
 	})
 
 	return r
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/LocalLink/internal/auth"
+	"github.com/LocalLink/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// maxSavedSearches caps how many searches one user can save.
+const maxSavedSearches = 20
+
+// Saved Search Handlers
+
+// CreateSavedSearch saves a search to be alerted about. The location is given as
+// coordinates or an address, and defaults to the caller's saved default location.
+func (h *Handler) CreateSavedSearch(w http.ResponseWriter, r *http.Request) {
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+	var search models.SavedSearch
+	if err := json.NewDecoder(r.Body).Decode(&search); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	search.UserID = userID
+	search.Query = strings.TrimSpace(search.Query)
+	search.Category = normalizeCategory(search.Category)
+	search.Name = strings.TrimSpace(search.Name)
+	if search.Name == "" {
+		search.Name = search.Query
+	}
+	if search.Name == "" {
+		search.Name = search.Category
+	}
+
+	if search.Latitude == 0 && search.Longitude == 0 && strings.TrimSpace(search.Address) == "" {
+		user, err := h.store.GetUserByID(r.Context(), userID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+		if user.DefaultLocation == nil {
+			respondWithError(w, http.StatusBadRequest, "Give latitude and longitude or an address, or save a default location first")
+			return
+		}
+		search.Latitude, search.Longitude = user.DefaultLocation.Latitude, user.DefaultLocation.Longitude
+	}
+	if msg := h.locate(r.Context(), search.Address, &search.Latitude, &search.Longitude); msg != "" {
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+	search.Address = ""
+
+	switch {
+	case search.Name == "":
+		respondWithError(w, http.StatusBadRequest, "Give a query, a category or a name")
+		return
+	case !validCoordinates(search.Latitude, search.Longitude):
+		respondWithError(w, http.StatusBadRequest, "latitude or longitude is out of range")
+		return
+	case search.RadiusMeters <= 0 || search.RadiusMeters > h.cfg.MaxSearchRadius:
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("radiusMeters must be from 1 to %d", h.cfg.MaxSearchRadius))
+		return
+	case search.MaxPrice != nil && *search.MaxPrice < 0:
+		respondWithError(w, http.StatusBadRequest, "maxPrice cannot be negative")
+		return
+	}
+
+	existing, err := h.store.GetSavedSearchesForUser(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	if len(existing) >= maxSavedSearches {
+		respondWithError(w, http.StatusConflict, fmt.Sprintf("You can save at most %d searches", maxSavedSearches))
+		return
+	}
+	if err := h.store.CreateSavedSearch(r.Context(), &search); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to save search")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, search)
+}
+
+func (h *Handler) GetSavedSearches(w http.ResponseWriter, r *http.Request) {
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+	searches, err := h.store.GetSavedSearchesForUser(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Could not fetch saved searches")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, searches)
+}
+
+func (h *Handler) DeleteSavedSearch(w http.ResponseWriter, r *http.Request) {
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+	searchID, _ := strconv.Atoi(chi.URLParam(r, "searchID"))
+	search, err := h.store.GetSavedSearch(r.Context(), searchID)
+	if err != nil || search.UserID != userID {
+		respondWithError(w, http.StatusNotFound, "Saved search not found")
+		return
+	}
+	if err := h.store.DeleteSavedSearch(r.Context(), searchID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete saved search")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// notifySavedSearches alerts the owners of saved searches a newly published product
+// matches. Failures are logged rather than failing the producer's request.
+func (h *Handler) notifySavedSearches(ctx context.Context, productID int) {
+	matches, err := h.store.MatchSavedSearches(ctx, productID)
+	if err != nil {
+		log.Printf("saved searches: matching product %d: %v", productID, err)
+		return
+	}
+	for _, m := range matches {
+		msg, _ := json.Marshal(map[string]interface{}{
+			"type":        "saved_search_match",
+			"searchId":    m.SearchID,
+			"searchName":  m.SearchName,
+			"productId":   m.ProductID,
+			"productName": m.ProductName,
+			"price":       m.Price,
+			"unit":        m.Unit,
+		})
+		h.hub.SendToUser(m.UserID, msg)
+	}
+}
+
+func normalizeCategory(category string) string {
+	return strings.ToLower(strings.TrimSpace(category))
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/LocalLink/internal/auth"
+	"github.com/LocalLink/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// startSession records a new login session and returns a token bound to it.
+func (h *Handler) startSession(r *http.Request, user *models.User, device string) (string, error) {
+	sessionID, err := auth.NewSessionID()
+	if err != nil {
+		return "", err
+	}
+	userAgent := r.UserAgent()
+	if device = strings.TrimSpace(device); device == "" {
+		device = describeDevice(userAgent)
+	}
+	session := models.Session{
+		ID:        sessionID,
+		UserID:    user.ID,
+		Device:    device,
+		IPAddress: clientIP(r),
+		UserAgent: userAgent,
+		ExpiresAt: time.Now().Add(auth.TokenTTL),
+	}
+	if err := h.store.CreateSession(r.Context(), &session); err != nil {
+		return "", err
+	}
+	return auth.GenerateJWT(user.ID, user.Role, sessionID, h.keys)
+}
+
+// describeDevice gives a short human-readable label for a user agent.
+func describeDevice(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	var platform, browser string
+	switch {
+	case strings.Contains(ua, "iphone"), strings.Contains(ua, "ipad"):
+		platform = "iOS"
+	case strings.Contains(ua, "android"):
+		platform = "Android"
+	case strings.Contains(ua, "windows"):
+		platform = "Windows"
+	case strings.Contains(ua, "mac os"):
+		platform = "macOS"
+	case strings.Contains(ua, "linux"):
+		platform = "Linux"
+	}
+	switch {
+	case strings.Contains(ua, "edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "firefox/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "chrome/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "safari/"):
+		browser = "Safari"
+	}
+	switch {
+	case browser != "" && platform != "":
+		return browser + " on " + platform
+	case browser != "":
+		return browser
+	case platform != "":
+		return platform
+	}
+	return "Unknown device"
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Session Handlers
+func (h *Handler) GetSessions(w http.ResponseWriter, r *http.Request) {
+	claims, err := auth.GetClaimsFromContext(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	sessions, err := h.store.GetActiveSessionsForUser(r.Context(), claims.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Could not fetch sessions")
+		return
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == claims.SessionID
+	}
+	respondWithJSON(w, http.StatusOK, sessions)
+}
+
+func (h *Handler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+	revoked, err := h.store.RevokeSession(r.Context(), userID, chi.URLParam(r, "sessionID"))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to end session")
+		return
+	}
+	if !revoked {
+		respondWithError(w, http.StatusNotFound, "Session not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokeOtherSessions signs the user out everywhere except the current session.
+func (h *Handler) RevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	claims, err := auth.GetClaimsFromContext(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	if err := h.store.RevokeOtherSessions(r.Context(), claims.UserID, claims.SessionID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to end sessions")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/LocalLink/internal/auth"
+	"github.com/LocalLink/internal/database"
+	"github.com/LocalLink/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Time Slot Handlers
+func (h *Handler) CreateTimeSlot(w http.ResponseWriter, r *http.Request) {
+	producerID, _ := auth.GetUserIDFromContext(r.Context())
+	var slot models.TimeSlot
+	if err := json.NewDecoder(r.Body).Decode(&slot); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	slot.ProducerID = producerID
+	switch {
+	case (slot.PickupPointID == nil) == (slot.DeliveryOptionID == nil):
+		respondWithError(w, http.StatusBadRequest, "Give either pickupPointId or deliveryOptionId")
+		return
+	case !slot.EndsAt.After(slot.StartsAt):
+		respondWithError(w, http.StatusBadRequest, "endsAt must be after startsAt")
+		return
+	case !slot.StartsAt.After(time.Now()):
+		respondWithError(w, http.StatusBadRequest, "startsAt must be in the future")
+		return
+	case slot.Capacity <= 0:
+		respondWithError(w, http.StatusBadRequest, "capacity must be positive")
+		return
+	}
+	if slot.PickupPointID != nil {
+		point, err := h.store.GetPickupPoint(r.Context(), *slot.PickupPointID)
+		if err != nil || point.ProducerID != producerID || !point.Active {
+			respondWithError(w, http.StatusBadRequest, "pickupPointId is not one of your pickup points")
+			return
+		}
+	} else {
+		option, err := h.store.GetDeliveryOption(r.Context(), *slot.DeliveryOptionID)
+		if err != nil || option.ProducerID != producerID || !option.Active {
+			respondWithError(w, http.StatusBadRequest, "deliveryOptionId is not one of your delivery options")
+			return
+		}
+	}
+	if err := h.store.CreateTimeSlot(r.Context(), &slot); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create time slot")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, slot)
+}
+
+func (h *Handler) GetPickupPointSlots(w http.ResponseWriter, r *http.Request) {
+	pointID, _ := strconv.Atoi(chi.URLParam(r, "pointID"))
+	slots, err := h.store.GetPickupPointSlots(r.Context(), pointID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Could not fetch time slots")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, slots)
+}
+
+func (h *Handler) GetDeliveryOptionSlots(w http.ResponseWriter, r *http.Request) {
+	optionID, _ := strconv.Atoi(chi.URLParam(r, "optionID"))
+	slots, err := h.store.GetDeliveryOptionSlots(r.Context(), optionID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Could not fetch time slots")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, slots)
+}
+
+func (h *Handler) DeleteTimeSlot(w http.ResponseWriter, r *http.Request) {
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+	slotID, _ := strconv.Atoi(chi.URLParam(r, "slotID"))
+	slot, err := h.store.GetTimeSlot(r.Context(), slotID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Time slot not found")
+		return
+	}
+	if slot.ProducerID != userID {
+		respondWithError(w, http.StatusForbidden, "You are not authorized to modify this time slot")
+		return
+	}
+	if err := h.store.DeleteTimeSlot(r.Context(), slotID); err != nil {
+		if errors.Is(err, database.ErrSlotBooked) {
+			respondWithError(w, http.StatusConflict, "This slot has bookings; reschedule them first")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete time slot")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RescheduleOrder moves an order to another slot. Either the buyer or the producer
+// can do it, and the other party is notified.
+func (h *Handler) RescheduleOrder(w http.ResponseWriter, r *http.Request) {
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+	orderID, _ := strconv.Atoi(chi.URLParam(r, "orderID"))
+	order, err := h.store.GetOrderByID(r.Context(), orderID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Order not found")
+		return
+	}
+	if order.BuyerID != userID && order.ProducerID != userID {
+		respondWithError(w, http.StatusForbidden, "You are not authorized to reschedule this order")
+		return
+	}
+	var input models.RescheduleOrderInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	updated, err := h.store.RescheduleOrder(r.Context(), orderID, input.SlotID)
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrOrderClosed):
+			respondWithError(w, http.StatusConflict, "This order can no longer be rescheduled")
+		case errors.Is(err, database.ErrSlotUnavailable):
+			respondWithError(w, http.StatusConflict, err.Error())
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Failed to reschedule order")
+		}
+		return
+	}
+
+	notify := order.BuyerID
+	if userID == order.BuyerID {
+		notify = order.ProducerID
+	}
+	msg, _ := json.Marshal(map[string]interface{}{
+		"type":     "order_rescheduled",
+		"orderId":  orderID,
+		"slotId":   input.SlotID,
+		"startsAt": updated.Fulfilment.SlotStartsAt,
+		"endsAt":   updated.Fulfilment.SlotEndsAt,
+	})
+	h.hub.SendToUser(notify, msg)
+	respondWithJSON(w, http.StatusOK, updated)
+}
@@ -0,0 +1,272 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LocalLink/internal/auth"
+	"github.com/LocalLink/internal/database"
+	"github.com/LocalLink/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Subscription Plan Handlers
+func (h *Handler) CreateSubscriptionPlan(w http.ResponseWriter, r *http.Request) {
+	producerID, _ := auth.GetUserIDFromContext(r.Context())
+	var plan models.SubscriptionPlan
+	if err := json.NewDecoder(r.Body).Decode(&plan); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	plan.ProducerID = producerID
+	plan.Name = strings.TrimSpace(plan.Name)
+	switch {
+	case plan.Name == "":
+		respondWithError(w, http.StatusBadRequest, "Plan name is required")
+		return
+	case plan.Price < 0:
+		respondWithError(w, http.StatusBadRequest, "Plan price cannot be negative")
+		return
+	case !models.Cadences[plan.Cadence]:
+		respondWithError(w, http.StatusBadRequest, "cadence must be weekly, fortnightly or monthly")
+		return
+	case plan.PickupDay < 0 || plan.PickupDay > 6:
+		respondWithError(w, http.StatusBadRequest, "pickupDay must be a weekday from 0 (Sunday) to 6 (Saturday)")
+		return
+	case len(plan.Items) == 0:
+		respondWithError(w, http.StatusBadRequest, "A plan needs at least one item")
+		return
+	}
+	if plan.PickupPointID == nil {
+		respondWithError(w, http.StatusBadRequest, "pickupPointId is required")
+		return
+	}
+	if !h.ownPickupPoint(r, producerID, *plan.PickupPointID) {
+		respondWithError(w, http.StatusBadRequest, "pickupPointId is not one of your pickup points")
+		return
+	}
+	for _, item := range plan.Items {
+		if msg := h.validatePlanItem(r, producerID, item); msg != "" {
+			respondWithError(w, http.StatusBadRequest, msg)
+			return
+		}
+	}
+	if err := h.store.CreateSubscriptionPlan(r.Context(), &plan); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create subscription plan")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, plan)
+}
+
+func (h *Handler) validatePlanItem(r *http.Request, producerID int, item models.OrderItemInput) string {
+	product, err := h.store.GetProductByID(r.Context(), item.ProductID)
+	if err != nil || product.ProducerID != producerID {
+		return fmt.Sprintf("Product %d is not one of your products", item.ProductID)
+	}
+	if item.Quantity <= 0 || !database.IsQuantityStep(item.Quantity, product.QuantityStep) {
+		return fmt.Sprintf("Quantity for product %d must be a positive multiple of %g", item.ProductID, product.QuantityStep)
+	}
+	if item.VariantID != nil {
+		variant, err := h.store.GetProductVariant(r.Context(), *item.VariantID)
+		if err != nil || variant.ProductID != product.ID {
+			return fmt.Sprintf("Variant %d does not belong to product %d", *item.VariantID, item.ProductID)
+		}
+	} else if len(product.Variants) > 0 {
+		return fmt.Sprintf("Product %d requires choosing a variant", item.ProductID)
+	}
+	return ""
+}
+
+func (h *Handler) ownPickupPoint(r *http.Request, producerID, pointID int) bool {
+	point, err := h.store.GetPickupPoint(r.Context(), pointID)
+	return err == nil && point.ProducerID == producerID && point.Active
+}
+
+// GetSubscriptionPlans lists a producer's active plans, given as ?producerId.
+func (h *Handler) GetSubscriptionPlans(w http.ResponseWriter, r *http.Request) {
+	producerID, err := strconv.Atoi(r.URL.Query().Get("producerId"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "producerId is required")
+		return
+	}
+	plans, err := h.store.GetSubscriptionPlansByProducer(r.Context(), producerID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Could not fetch subscription plans")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, plans)
+}
+
+func (h *Handler) GetSubscriptionPlan(w http.ResponseWriter, r *http.Request) {
+	planID, _ := strconv.Atoi(chi.URLParam(r, "planID"))
+	plan, err := h.store.GetSubscriptionPlan(r.Context(), planID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Subscription plan not found")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, plan)
+}
+
+// UpdateSubscriptionPlan renames or reprices a plan, or moves its pickups to another
+// pickup point. Changes apply from the next generated order.
+func (h *Handler) UpdateSubscriptionPlan(w http.ResponseWriter, r *http.Request) {
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+	planID, _ := strconv.Atoi(chi.URLParam(r, "planID"))
+	plan, err := h.store.GetSubscriptionPlan(r.Context(), planID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Subscription plan not found")
+		return
+	}
+	if plan.ProducerID != userID {
+		respondWithError(w, http.StatusForbidden, "You are not authorized to modify this plan")
+		return
+	}
+	var input models.UpdateSubscriptionPlanInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if input.Name != nil {
+		name := strings.TrimSpace(*input.Name)
+		if name == "" {
+			respondWithError(w, http.StatusBadRequest, "Plan name is required")
+			return
+		}
+		input.Name = &name
+	}
+	if input.Price != nil && *input.Price < 0 {
+		respondWithError(w, http.StatusBadRequest, "Plan price cannot be negative")
+		return
+	}
+	if input.PickupPointID != nil && !h.ownPickupPoint(r, userID, *input.PickupPointID) {
+		respondWithError(w, http.StatusBadRequest, "pickupPointId is not one of your pickup points")
+		return
+	}
+	updated, err := h.store.UpdateSubscriptionPlan(r.Context(), planID, input)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to update subscription plan")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, updated)
+}
+
+// DeactivateSubscriptionPlan closes a plan to new subscribers.
+func (h *Handler) DeactivateSubscriptionPlan(w http.ResponseWriter, r *http.Request) {
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+	planID, _ := strconv.Atoi(chi.URLParam(r, "planID"))
+	plan, err := h.store.GetSubscriptionPlan(r.Context(), planID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Subscription plan not found")
+		return
+	}
+	if plan.ProducerID != userID {
+		respondWithError(w, http.StatusForbidden, "You are not authorized to modify this plan")
+		return
+	}
+	if err := h.store.DeactivateSubscriptionPlan(r.Context(), planID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to deactivate plan")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Subscription Handlers
+func (h *Handler) Subscribe(w http.ResponseWriter, r *http.Request) {
+	buyerID, _ := auth.GetUserIDFromContext(r.Context())
+	planID, _ := strconv.Atoi(chi.URLParam(r, "planID"))
+	plan, err := h.store.GetSubscriptionPlan(r.Context(), planID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Subscription plan not found")
+		return
+	}
+	if !plan.Active {
+		respondWithError(w, http.StatusConflict, "This plan is no longer taking subscribers")
+		return
+	}
+	sub, err := h.store.CreateSubscription(r.Context(), plan, buyerID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to subscribe")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, sub)
+}
+
+func (h *Handler) GetMySubscriptions(w http.ResponseWriter, r *http.Request) {
+	buyerID, _ := auth.GetUserIDFromContext(r.Context())
+	subs, err := h.store.GetSubscriptionsForBuyer(r.Context(), buyerID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Could not fetch subscriptions")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, subs)
+}
+
+func (h *Handler) PauseSubscription(w http.ResponseWriter, r *http.Request) {
+	h.changeSubscription(w, r, func(sub *models.Subscription) string {
+		if sub.Status != models.SubscriptionActive {
+			return "Only active subscriptions can be paused"
+		}
+		sub.Status = models.SubscriptionPaused
+		return ""
+	})
+}
+
+func (h *Handler) ResumeSubscription(w http.ResponseWriter, r *http.Request) {
+	h.changeSubscription(w, r, func(sub *models.Subscription) string {
+		if sub.Status != models.SubscriptionPaused {
+			return "Only paused subscriptions can be resumed"
+		}
+		sub.Status = models.SubscriptionActive
+		if first := database.FirstPickup(time.Now(), sub.Plan.PickupDay); sub.NextPickupOn.Before(first) {
+			sub.NextPickupOn = first
+		}
+		return ""
+	})
+}
+
+// SkipSubscription skips the next pickup; the order for it has not been generated yet.
+func (h *Handler) SkipSubscription(w http.ResponseWriter, r *http.Request) {
+	h.changeSubscription(w, r, func(sub *models.Subscription) string {
+		if sub.Status != models.SubscriptionActive {
+			return "Only active subscriptions can skip a pickup"
+		}
+		sub.NextPickupOn = database.NextPickup(sub.NextPickupOn, sub.Plan.Cadence, sub.Plan.PickupDay)
+		return ""
+	})
+}
+
+func (h *Handler) CancelSubscription(w http.ResponseWriter, r *http.Request) {
+	h.changeSubscription(w, r, func(sub *models.Subscription) string {
+		if sub.Status == models.SubscriptionCancelled {
+			return "Subscription is already cancelled"
+		}
+		sub.Status = models.SubscriptionCancelled
+		return ""
+	})
+}
+
+// changeSubscription loads the caller's subscription, applies change, which returns a
+// conflict message when the change is not allowed, and saves it.
+func (h *Handler) changeSubscription(w http.ResponseWriter, r *http.Request, change func(sub *models.Subscription) string) {
+	buyerID, _ := auth.GetUserIDFromContext(r.Context())
+	subscriptionID, _ := strconv.Atoi(chi.URLParam(r, "subscriptionID"))
+	sub, err := h.store.GetSubscription(r.Context(), subscriptionID)
+	if err != nil || sub.BuyerID != buyerID {
+		respondWithError(w, http.StatusNotFound, "Subscription not found")
+		return
+	}
+	if msg := change(sub); msg != "" {
+		respondWithError(w, http.StatusConflict, msg)
+		return
+	}
+	if err := h.store.UpdateSubscription(r.Context(), sub); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to update subscription")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, sub)
+}
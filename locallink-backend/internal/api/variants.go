@@ -0,0 +1,149 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/LocalLink/internal/auth"
+	"github.com/LocalLink/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Product Variant Handlers
+func (h *Handler) CreateProductVariant(w http.ResponseWriter, r *http.Request) {
+	product, ok := h.ownedProduct(w, r)
+	if !ok {
+		return
+	}
+	var input models.ProductVariantInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if product.BatchTracked {
+		respondWithError(w, http.StatusConflict, "Products sold in batches cannot have variants")
+		return
+	}
+	if product.Preorder || product.PreorderedQuantity > 0 {
+		respondWithError(w, http.StatusConflict, "Variants cannot be added while the product has pre-orders waiting")
+		return
+	}
+	variant := models.ProductVariant{ProductID: product.ID}
+	if input.Name != nil {
+		variant.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.Price != nil {
+		variant.Price = *input.Price
+	}
+	if input.Quantity != nil {
+		variant.Quantity = *input.Quantity
+	}
+	if msg := validateVariant(variant.Name, variant.Price, variant.Quantity); msg != "" {
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+	if err := h.store.CreateProductVariant(r.Context(), &variant); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create variant")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, variant)
+}
+
+func (h *Handler) UpdateProductVariant(w http.ResponseWriter, r *http.Request) {
+	product, ok := h.ownedProduct(w, r)
+	if !ok {
+		return
+	}
+	variantID, _ := strconv.Atoi(chi.URLParam(r, "variantID"))
+	variant, err := h.store.GetProductVariant(r.Context(), variantID)
+	if err != nil || variant.ProductID != product.ID {
+		respondWithError(w, http.StatusNotFound, "Variant not found")
+		return
+	}
+	var input models.ProductVariantInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	name, price, quantity := variant.Name, variant.Price, variant.Quantity
+	if input.Name != nil {
+		trimmed := strings.TrimSpace(*input.Name)
+		input.Name, name = &trimmed, trimmed
+	}
+	if input.Price != nil {
+		price = *input.Price
+	}
+	if input.Quantity != nil {
+		quantity = *input.Quantity
+	}
+	if msg := validateVariant(name, price, quantity); msg != "" {
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+	updated, err := h.store.UpdateProductVariant(r.Context(), variantID, input)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to update variant")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, updated)
+}
+
+func (h *Handler) DeleteProductVariant(w http.ResponseWriter, r *http.Request) {
+	product, ok := h.ownedProduct(w, r)
+	if !ok {
+		return
+	}
+	variantID, _ := strconv.Atoi(chi.URLParam(r, "variantID"))
+	variant, err := h.store.GetProductVariant(r.Context(), variantID)
+	if err != nil || variant.ProductID != product.ID {
+		respondWithError(w, http.StatusNotFound, "Variant not found")
+		return
+	}
+	if err := h.store.DeleteProductVariant(r.Context(), variantID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete variant")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ownedProduct loads the product named in the URL and checks the caller produces it,
+// writing the error response itself when not.
+func (h *Handler) ownedProduct(w http.ResponseWriter, r *http.Request) (*models.Product, bool) {
+	userID, _ := auth.GetUserIDFromContext(r.Context())
+	productID, _ := strconv.Atoi(chi.URLParam(r, "productID"))
+	product, err := h.store.GetProductByID(r.Context(), productID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Product not found")
+		return nil, false
+	}
+	if product.ProducerID != userID {
+		respondWithError(w, http.StatusForbidden, "You are not authorized to modify this product")
+		return nil, false
+	}
+	return product, true
+}
+
+func validateUnit(unit string, step float64) string {
+	if !models.Units[unit] {
+		return "Unknown unit: " + unit
+	}
+	if step <= 0 {
+		return "quantityStep must be positive"
+	}
+	return ""
+}
+
+func validateVariant(name string, price, quantity float64) string {
+	switch {
+	case name == "":
+		return "Variant name is required"
+	case price < 0:
+		return "Variant price cannot be negative"
+	case quantity < 0:
+		return "Variant quantity cannot be negative"
+	}
+	return ""
+}
@@ -2,21 +2,30 @@ package auth
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 	"time"
 
-	"github.com/LocalLink/internal/config"
-
-	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type contextKey string
 
-const UserIDKey contextKey = "userID"
+const (
+	UserIDKey contextKey = "userID"
+	ClaimsKey contextKey = "claims"
+)
+
+// TokenTTL is the lifetime of access tokens and of the sessions they belong to.
+const TokenTTL = 24 * time.Hour
+
+const (
+	mfaChallengeTTL = 5 * time.Minute
+	mfaAudience     = "locallink-mfa"
+)
 
 func HashPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
@@ -28,63 +37,123 @@ func CheckPasswordHash(password, hash string) bool {
 	return err == nil
 }
 
-func GenerateJWT(userID int, cfg *config.Config) (string, error) {
-	claims := jwt.MapClaims{
-		"authorized": true,
-		"userID":     userID,
-		"exp":        time.Now().Add(time.Hour * 24).Unix(),
+func GenerateJWT(userID int, role, sessionID string, keys *KeySet) (string, error) {
+	return keys.Sign(keys.newClaims(userID, role, sessionID, keys.audience, TokenTTL))
+}
+
+// GenerateMFAChallengeToken issues a short-lived token proving the password step
+// succeeded. Its audience differs from the API's, so AuthMiddleware will not accept it.
+// It carries a unique ID so it can be spent once the login completes.
+func GenerateMFAChallengeToken(userID int, role string, keys *KeySet) (string, error) {
+	challengeID, err := NewSessionID()
+	if err != nil {
+		return "", err
+	}
+	claims := keys.newClaims(userID, role, "", mfaAudience, mfaChallengeTTL)
+	claims.ID = challengeID
+	return keys.Sign(claims)
+}
+
+func ParseMFAChallengeToken(tokenString string, keys *KeySet) (*Claims, error) {
+	claims, err := keys.parseClaims(tokenString, mfaAudience)
+	if err != nil || claims.ID == "" {
+		return nil, errors.New("invalid or expired MFA token")
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(cfg.JWTSecret))
+	return claims, nil
+}
+
+// SessionStore reports whether a token's session is still active.
+type SessionStore interface {
+	TouchSession(ctx context.Context, sessionID string, userID int) (bool, error)
 }
 
-func AuthMiddleware(cfg *config.Config) func(http.Handler) http.Handler {
+func AuthMiddleware(keys *KeySet, sessions SessionStore) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			tokenString := r.URL.Query().Get("token")
 			if tokenString == "" {
 				authHeader := r.Header.Get("Authorization")
 				if authHeader == "" {
-					http.Error(w, "Authorization required", http.StatusUnauthorized)
+					respondUnauthorized(w, "", "Authorization required")
 					return
 				}
 				tokenString = strings.TrimPrefix(authHeader, "Bearer ")
 			}
 
 			if tokenString == "" {
-				http.Error(w, "Could not find token", http.StatusUnauthorized)
+				respondUnauthorized(w, "invalid_request", "Could not find token")
 				return
 			}
 
-			token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-					return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-				}
-				return []byte(cfg.JWTSecret), nil
-			})
-
-			if err != nil || !token.Valid {
-				http.Error(w, "Invalid token", http.StatusUnauthorized)
+			claims, err := keys.parseClaims(tokenString, keys.audience)
+			if err != nil {
+				respondUnauthorized(w, "invalid_token", "Invalid token")
 				return
 			}
 
-			if claims, ok := token.Claims.(jwt.MapClaims); ok {
-				if userIDFloat, ok := claims["userID"].(float64); ok {
-					userID := int(userIDFloat)
-					ctx := context.WithValue(r.Context(), UserIDKey, userID)
-					next.ServeHTTP(w, r.WithContext(ctx))
+			// Legacy tokens predate sessions and stay valid until they expire.
+			if !claims.Legacy {
+				active, err := sessions.TouchSession(r.Context(), claims.SessionID, claims.UserID)
+				if err != nil {
+					writeJSONError(w, http.StatusInternalServerError, "Database error")
+					return
+				}
+				if !active {
+					respondUnauthorized(w, "invalid_token", "Session has been terminated")
 					return
 				}
 			}
-			http.Error(w, "Invalid token claims", http.StatusUnauthorized)
+
+			ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
+			ctx = context.WithValue(ctx, ClaimsKey, claims)
+			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+// OptionalAuthMiddleware authenticates requests that carry a token exactly like
+// AuthMiddleware, and lets requests without one through anonymously.
+func OptionalAuthMiddleware(keys *KeySet, sessions SessionStore) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		authenticated := AuthMiddleware(keys, sessions)(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("token") == "" && r.Header.Get("Authorization") == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			authenticated.ServeHTTP(w, r)
+		})
+	}
+}
+
+// respondUnauthorized writes a 401 with an RFC 6750 challenge and the API's JSON error body.
+func respondUnauthorized(w http.ResponseWriter, errorCode, message string) {
+	challenge := `Bearer realm="locallink"`
+	if errorCode != "" {
+		challenge += fmt.Sprintf(`, error="%s", error_description="%s"`, errorCode, message)
+	}
+	w.Header().Set("WWW-Authenticate", challenge)
+	writeJSONError(w, http.StatusUnauthorized, message)
+}
+
+func writeJSONError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
 func GetUserIDFromContext(ctx context.Context) (int, error) {
 	userID, ok := ctx.Value(UserIDKey).(int)
 	if !ok {
 		return 0, errors.New("no userID found in context")
 	}
 	return userID, nil
-}
\ No newline at end of file
+}
+
+func GetClaimsFromContext(ctx context.Context) (*Claims, error) {
+	claims, ok := ctx.Value(ClaimsKey).(*Claims)
+	if !ok {
+		return nil, errors.New("no claims found in context")
+	}
+	return claims, nil
+}
@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	clockSkewLeeway   = 30 * time.Second
+	oidcStateTTL      = 10 * time.Minute
+	oidcStateAudience = "locallink-oidc-state"
+)
+
+// Claims are the claims carried by every token we issue. The user ID travels as the
+// standard subject claim and is decoded into UserID after validation.
+type Claims struct {
+	Role      string `json:"role"`
+	SessionID string `json:"sid,omitempty"`
+	UserID    int    `json:"-"`
+	// Legacy is set on API tokens issued before typed claims; see parseLegacyClaims.
+	Legacy bool `json:"-"`
+	jwt.RegisteredClaims
+}
+
+// legacyClaims are the claims of API tokens issued before typed claims: the user ID
+// in its own claim, and no issuer, audience, role or session.
+type legacyClaims struct {
+	Authorized bool `json:"authorized"`
+	UserID     int  `json:"userID"`
+	jwt.RegisteredClaims
+}
+
+func (ks *KeySet) newClaims(userID int, role, sessionID, audience string, ttl time.Duration) *Claims {
+	now := time.Now()
+	return &Claims{
+		Role:      role,
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    ks.issuer,
+			Subject:   strconv.Itoa(userID),
+			Audience:  jwt.ClaimStrings{audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+}
+
+// parseClaims verifies the signature and requires a matching issuer and audience,
+// an expiry, and valid iat/nbf times. Until the legacy cutoff, API tokens issued
+// before issuer and audience checks are accepted too.
+func (ks *KeySet) parseClaims(tokenString, audience string) (*Claims, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, ks.Keyfunc,
+		jwt.WithValidMethods([]string{"RS256", "EdDSA", "HS256"}),
+		jwt.WithIssuer(ks.issuer),
+		jwt.WithAudience(audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(clockSkewLeeway),
+	)
+	if err != nil {
+		if audience == ks.audience && time.Now().Before(ks.legacyCutoff) {
+			if legacy, legacyErr := ks.parseLegacyClaims(tokenString); legacyErr == nil {
+				return legacy, nil
+			}
+		}
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+	userID, err := strconv.Atoi(claims.Subject)
+	if err != nil || userID <= 0 {
+		return nil, errors.New("invalid subject claim")
+	}
+	claims.UserID = userID
+	return claims, nil
+}
+
+// parseLegacyClaims accepts an unexpired API token in the old format that expires
+// before the legacy cutoff. It must carry no issuer or audience, so a current token
+// meant for another audience can't pass as a legacy one.
+func (ks *KeySet) parseLegacyClaims(tokenString string) (*Claims, error) {
+	legacy := &legacyClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, legacy, ks.Keyfunc,
+		jwt.WithValidMethods([]string{"RS256", "EdDSA", "HS256"}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(clockSkewLeeway),
+	)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case legacy.Issuer != "" || len(legacy.Audience) > 0:
+		return nil, errors.New("not a legacy token")
+	case !legacy.Authorized || legacy.UserID <= 0:
+		return nil, errors.New("invalid legacy token claims")
+	case legacy.ExpiresAt.After(ks.legacyCutoff):
+		return nil, errors.New("legacy token outlives the cutoff")
+	}
+	return &Claims{
+		UserID: legacy.UserID,
+		Legacy: true,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.Itoa(legacy.UserID),
+			ExpiresAt: legacy.ExpiresAt,
+		},
+	}, nil
+}
+
+func NewSessionID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// OIDCStateClaims carry the per-login secrets of an OIDC authorization code flow
+// between the redirect to the provider and the callback, in an HttpOnly cookie.
+type OIDCStateClaims struct {
+	Provider string `json:"provider"`
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	jwt.RegisteredClaims
+}
+
+func GenerateOIDCStateToken(state OIDCStateClaims, keys *KeySet) (string, error) {
+	now := time.Now()
+	state.RegisteredClaims = jwt.RegisteredClaims{
+		Issuer:    keys.issuer,
+		Audience:  jwt.ClaimStrings{oidcStateAudience},
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(oidcStateTTL)),
+	}
+	return keys.Sign(&state)
+}
+
+func ParseOIDCStateToken(tokenString string, keys *KeySet) (*OIDCStateClaims, error) {
+	state := &OIDCStateClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, state, keys.Keyfunc,
+		jwt.WithValidMethods([]string{"RS256", "EdDSA", "HS256"}),
+		jwt.WithIssuer(keys.issuer),
+		jwt.WithAudience(oidcStateAudience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return state, nil
+}
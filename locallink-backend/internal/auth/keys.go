@@ -0,0 +1,214 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/LocalLink/internal/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// KeySet holds the key used to sign new tokens and every key still accepted for verification.
+//
+// Keys are read from JWT_KEYS_DIR, one PEM file per key named <kid>.pem. Private keys
+// (PKCS#8, or PKCS#1 for RSA) can sign and verify; public keys (PKIX) only verify.
+// JWT_ACTIVE_KEY_ID picks the signing key. To rotate:
+//
+//  1. Generate a new key with `go run ./cmd/keygen -dir $JWT_KEYS_DIR`.
+//  2. Restart with JWT_ACTIVE_KEY_ID set to the new kid. Tokens signed with the
+//     old key still verify because its file is still in the directory.
+//  3. Once the token lifetime has passed, delete the old key file (or replace it
+//     with its public half) and restart.
+//
+// While JWT_SECRET is set, HS256 tokens without a kid are still accepted so that
+// sessions issued before the migration stay valid. If no active key is configured,
+// new tokens keep being signed with HS256.
+type KeySet struct {
+	// issuer and audience are stamped into and required of every token we issue.
+	issuer      string
+	audience    string
+	activeKeyID string
+	signer      crypto.Signer
+	verifiers   map[string]crypto.PublicKey
+	hmacSecret  []byte
+
+	// legacyCutoff ends acceptance of API tokens issued before typed claims.
+	legacyCutoff time.Time
+}
+
+func NewKeySet(cfg *config.Config) (*KeySet, error) {
+	ks := &KeySet{
+		issuer:       cfg.JWTIssuer,
+		audience:     cfg.JWTAudience,
+		legacyCutoff: cfg.JWTLegacyCutoff,
+		verifiers:    make(map[string]crypto.PublicKey),
+	}
+	if ks.legacyCutoff.IsZero() {
+		ks.legacyCutoff = time.Now().Add(TokenTTL)
+	}
+	if cfg.JWTSecret != "" {
+		ks.hmacSecret = []byte(cfg.JWTSecret)
+	}
+
+	signers := make(map[string]crypto.Signer)
+	if cfg.JWTKeysDir != "" {
+		files, err := filepath.Glob(filepath.Join(cfg.JWTKeysDir, "*.pem"))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			kid := strings.TrimSuffix(filepath.Base(file), ".pem")
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			signer, public, err := parseKeyPEM(data)
+			if err != nil {
+				return nil, fmt.Errorf("key %s: %w", kid, err)
+			}
+			if signer != nil {
+				signers[kid] = signer
+			}
+			ks.verifiers[kid] = public
+		}
+	}
+
+	if cfg.JWTActiveKeyID != "" {
+		signer, ok := signers[cfg.JWTActiveKeyID]
+		if !ok {
+			return nil, fmt.Errorf("active key %q not found among private keys in %q", cfg.JWTActiveKeyID, cfg.JWTKeysDir)
+		}
+		ks.activeKeyID = cfg.JWTActiveKeyID
+		ks.signer = signer
+	} else if ks.hmacSecret == nil {
+		return nil, errors.New("no signing key configured: set JWT_ACTIVE_KEY_ID or JWT_SECRET")
+	}
+	return ks, nil
+}
+
+// Sign signs the claims with the active key, or with HS256 when none is configured.
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	if ks.signer == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(ks.hmacSecret)
+	}
+	token := jwt.NewWithClaims(signingMethodFor(ks.signer.Public()), claims)
+	token.Header["kid"] = ks.activeKeyID
+	return token.SignedString(ks.signer)
+}
+
+// Keyfunc resolves the verification key for a token by its kid header, falling back
+// to the legacy HS256 secret for tokens without one.
+func (ks *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok && ks.hmacSecret != nil {
+			return ks.hmacSecret, nil
+		}
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	public, ok := ks.verifiers[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if token.Method != signingMethodFor(public) {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return public, nil
+}
+
+// JWK is a single public key in JSON Web Key format.
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS returns the public verification keys for publishing at /.well-known/jwks.json.
+func (ks *KeySet) JWKS() map[string][]JWK {
+	kids := make([]string, 0, len(ks.verifiers))
+	for kid := range ks.verifiers {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	keys := make([]JWK, 0, len(kids))
+	for _, kid := range kids {
+		switch public := ks.verifiers[kid].(type) {
+		case *rsa.PublicKey:
+			keys = append(keys, JWK{
+				Kty: "RSA", Use: "sig", Alg: "RS256", Kid: kid,
+				N: base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+				E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			keys = append(keys, JWK{
+				Kty: "OKP", Use: "sig", Alg: "EdDSA", Kid: kid, Crv: "Ed25519",
+				X: base64.RawURLEncoding.EncodeToString(public),
+			})
+		}
+	}
+	return map[string][]JWK{"keys": keys}
+}
+
+func signingMethodFor(public crypto.PublicKey) jwt.SigningMethod {
+	switch public.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA
+	}
+	return nil
+}
+
+func parseKeyPEM(data []byte) (crypto.Signer, crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, nil, errors.New("no PEM block found")
+	}
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok || signingMethodFor(signer.Public()) == nil {
+			return nil, nil, errors.New("unsupported private key type; use RSA or Ed25519")
+		}
+		return signer, signer.Public(), nil
+	case "RSA PRIVATE KEY":
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		return key, key.Public(), nil
+	case "PUBLIC KEY":
+		public, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		if signingMethodFor(public) == nil {
+			return nil, nil, errors.New("unsupported public key type; use RSA or Ed25519")
+		}
+		return nil, public, nil
+	}
+	return nil, nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+}
@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+// The shared secret of the RFC 4226 and RFC 6238 SHA-1 test vectors.
+var rfcSecret = []byte("12345678901234567890")
+
+func TestHOTP(t *testing.T) {
+	// RFC 4226, Appendix D.
+	want := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+	for counter, code := range want {
+		if got := hotp(rfcSecret, uint64(counter), 6); got != code {
+			t.Errorf("hotp(counter %d) = %s, want %s", counter, got, code)
+		}
+	}
+}
+
+func TestTOTPVectors(t *testing.T) {
+	// RFC 6238, Appendix B, SHA-1 rows.
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+	for _, tt := range tests {
+		if got := hotp(rfcSecret, uint64(tt.unix/totpPeriod), 8); got != tt.code {
+			t.Errorf("TOTP at %d = %s, want %s", tt.unix, got, tt.code)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret := base32NoPadding.EncodeToString(rfcSecret)
+	// The six-digit code for the step containing T=59, per RFC 6238.
+	const code = "287082"
+	tests := []struct {
+		name string
+		unix int64
+		code string
+		want bool
+	}{
+		{"current step", 59, code, true},
+		{"one step late", 59 + totpPeriod, code, true},
+		{"one step early", 59 - totpPeriod, code, true},
+		{"two steps late", 59 + 2*totpPeriod, code, false},
+		{"wrong code", 59, "287083", false},
+		{"too short", 59, "28708", false},
+		{"surrounding spaces", 59, " " + code + " ", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, got := ValidateTOTP(secret, tt.code, time.Unix(tt.unix, 0))
+			if got != tt.want {
+				t.Fatalf("ValidateTOTP = %v, want %v", got, tt.want)
+			}
+			if got && step != 59/totpPeriod {
+				t.Errorf("ValidateTOTP step = %d, want %d", step, 59/totpPeriod)
+			}
+		})
+	}
+}
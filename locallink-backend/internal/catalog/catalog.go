@@ -0,0 +1,306 @@
+// Package catalog reads and writes a producer's product catalogue as CSV or JSON Lines
+// for bulk import and export.
+package catalog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/LocalLink/internal/models"
+)
+
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+// MaxRows caps the size of a single import.
+const MaxRows = 5000
+
+// Columns is the CSV header, in order. JSON Lines records use the same fields under
+// the product's JSON names.
+var Columns = []string{"sku", "name", "description", "price", "quantity", "unit", "quantity_step", "status", "latitude", "longitude"}
+
+var requiredColumns = []string{"sku", "name", "price"}
+
+// Row is one product read from an import file. Unit, quantity step, status and
+// location are optional; HasLocation reports whether both coordinates were given.
+// Given holds the other optional columns the row set, by column name, so updates can
+// leave the rest alone.
+type Row struct {
+	Line        int
+	Product     models.Product
+	HasLocation bool
+	Given       map[string]bool
+}
+
+// RowError describes why one line of an import was rejected.
+type RowError struct {
+	Line    int    `json:"line"`
+	SKU     string `json:"sku,omitempty"`
+	Message string `json:"message"`
+}
+
+type record struct {
+	SKU          string   `json:"sku"`
+	Name         string   `json:"name"`
+	Description  *string  `json:"description,omitempty"`
+	Price        *float64 `json:"price"`
+	Quantity     *float64 `json:"quantity,omitempty"`
+	Unit         string   `json:"unit,omitempty"`
+	QuantityStep *float64 `json:"quantityStep,omitempty"`
+	Status       string   `json:"status,omitempty"`
+	Latitude     *float64 `json:"latitude,omitempty"`
+	Longitude    *float64 `json:"longitude,omitempty"`
+}
+
+func (rec record) row(line int) Row {
+	row := Row{Line: line, Given: make(map[string]bool), Product: models.Product{
+		SKU:    strings.TrimSpace(rec.SKU),
+		Name:   strings.TrimSpace(rec.Name),
+		Unit:   strings.TrimSpace(rec.Unit),
+		Status: strings.TrimSpace(rec.Status),
+	}}
+	if rec.Price != nil {
+		row.Product.Price = *rec.Price
+	}
+	if rec.Description != nil {
+		row.Product.Description = *rec.Description
+		row.Given["description"] = true
+	}
+	if rec.Quantity != nil {
+		row.Product.Quantity = *rec.Quantity
+		row.Given["quantity"] = true
+	}
+	if rec.QuantityStep != nil {
+		row.Product.QuantityStep = *rec.QuantityStep
+		row.Given["quantity_step"] = true
+	}
+	row.Given["unit"] = row.Product.Unit != ""
+	row.Given["status"] = row.Product.Status != ""
+	if rec.Latitude != nil && rec.Longitude != nil {
+		row.Product.Latitude, row.Product.Longitude = *rec.Latitude, *rec.Longitude
+		row.HasLocation = true
+	}
+	return row
+}
+
+// Read parses an import file in the given format. Lines that cannot be parsed are
+// reported as RowErrors; an error is returned only when the file as a whole is unusable.
+func Read(r io.Reader, format string) ([]Row, []RowError, error) {
+	switch format {
+	case FormatCSV:
+		return readCSV(r)
+	case FormatJSONL:
+		return readJSONL(r)
+	}
+	return nil, nil, fmt.Errorf("unsupported format %q", format)
+}
+
+func readCSV(r io.Reader) ([]Row, []RowError, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, errors.New("could not read the CSV header")
+	}
+	index := make(map[string]int)
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, name := range requiredColumns {
+		if _, ok := index[name]; !ok {
+			return nil, nil, fmt.Errorf("the CSV header is missing the %q column", name)
+		}
+	}
+
+	var rows []Row
+	var rowErrors []RowError
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				rowErrors = append(rowErrors, RowError{Line: parseErr.Line, Message: parseErr.Err.Error()})
+				continue
+			}
+			return nil, nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		if len(rows)+len(rowErrors) >= MaxRows {
+			return nil, nil, fmt.Errorf("imports are limited to %d rows", MaxRows)
+		}
+
+		get := func(name string) string {
+			if i, ok := index[name]; ok && i < len(fields) {
+				return strings.TrimSpace(fields[i])
+			}
+			return ""
+		}
+		var rec record
+		rec.SKU, rec.Name = get("sku"), get("name")
+		rec.Unit, rec.Status = get("unit"), get("status")
+		if _, ok := index["description"]; ok {
+			description := get("description")
+			rec.Description = &description
+		}
+		var problems []string
+		// number returns nil for an empty cell, which leaves the field unchanged.
+		number := func(name string) *float64 {
+			value := get(name)
+			if value == "" {
+				return nil
+			}
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s %q is not a number", name, value))
+				return nil
+			}
+			return &n
+		}
+		rec.Price = number("price")
+		rec.Quantity = number("quantity")
+		rec.QuantityStep = number("quantity_step")
+		if get("latitude") != "" || get("longitude") != "" {
+			rec.Latitude, rec.Longitude = number("latitude"), number("longitude")
+		}
+		if get("price") == "" {
+			problems = append(problems, "price is required")
+		}
+		if len(problems) > 0 {
+			rowErrors = append(rowErrors, RowError{Line: line, SKU: rec.SKU, Message: strings.Join(problems, "; ")})
+			continue
+		}
+		rows = append(rows, rec.row(line))
+	}
+	return rows, rowErrors, nil
+}
+
+func readJSONL(r io.Reader) ([]Row, []RowError, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	var rows []Row
+	var rowErrors []RowError
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		if len(rows)+len(rowErrors) >= MaxRows {
+			return nil, nil, fmt.Errorf("imports are limited to %d rows", MaxRows)
+		}
+		var rec record
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&rec); err != nil {
+			rowErrors = append(rowErrors, RowError{Line: line, Message: "invalid JSON: " + err.Error()})
+			continue
+		}
+		if rec.Price == nil {
+			rowErrors = append(rowErrors, RowError{Line: line, SKU: strings.TrimSpace(rec.SKU), Message: "price is required"})
+			continue
+		}
+		rows = append(rows, rec.row(line))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	return rows, rowErrors, nil
+}
+
+// Validate checks each row on its own and for SKUs repeated within the file.
+func Validate(rows []Row) []RowError {
+	var rowErrors []RowError
+	seen := make(map[string]int)
+	for _, row := range rows {
+		p := row.Product
+		var problems []string
+		if p.SKU == "" {
+			problems = append(problems, "sku is required")
+		} else if first, ok := seen[p.SKU]; ok {
+			problems = append(problems, fmt.Sprintf("sku is repeated from line %d", first))
+		} else {
+			seen[p.SKU] = row.Line
+		}
+		if p.Name == "" {
+			problems = append(problems, "name is required")
+		}
+		if p.Price < 0 {
+			problems = append(problems, "price cannot be negative")
+		}
+		if p.Quantity < 0 {
+			problems = append(problems, "quantity cannot be negative")
+		}
+		if p.Unit != "" && !models.Units[p.Unit] {
+			problems = append(problems, fmt.Sprintf("unknown unit %q", p.Unit))
+		}
+		if row.Given["quantity_step"] && p.QuantityStep <= 0 {
+			problems = append(problems, "quantity_step must be positive")
+		}
+		if p.Status != "" && !models.ProductStatuses[p.Status] {
+			problems = append(problems, fmt.Sprintf("unknown status %q", p.Status))
+		}
+		if row.HasLocation && (p.Latitude < -90 || p.Latitude > 90 || p.Longitude < -180 || p.Longitude > 180) {
+			problems = append(problems, "latitude or longitude is out of range")
+		}
+		if len(problems) > 0 {
+			rowErrors = append(rowErrors, RowError{Line: row.Line, SKU: p.SKU, Message: strings.Join(problems, "; ")})
+		}
+	}
+	return rowErrors
+}
+
+// Write exports products in the given format, in the same shape Read accepts.
+func Write(w io.Writer, format string, products []models.Product) error {
+	switch format {
+	case FormatCSV:
+		return writeCSV(w, products)
+	case FormatJSONL:
+		return writeJSONL(w, products)
+	}
+	return fmt.Errorf("unsupported format %q", format)
+}
+
+func writeCSV(w io.Writer, products []models.Product) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(Columns); err != nil {
+		return err
+	}
+	formatNumber := func(n float64) string { return strconv.FormatFloat(n, 'f', -1, 64) }
+	for _, p := range products {
+		err := writer.Write([]string{
+			p.SKU, p.Name, p.Description, formatNumber(p.Price), formatNumber(p.Quantity), p.Unit,
+			formatNumber(p.QuantityStep), p.Status, formatNumber(p.Latitude), formatNumber(p.Longitude),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func writeJSONL(w io.Writer, products []models.Product) error {
+	encoder := json.NewEncoder(w)
+	for _, p := range products {
+		rec := record{
+			SKU: p.SKU, Name: p.Name, Description: &p.Description, Price: &p.Price, Quantity: &p.Quantity,
+			Unit: p.Unit, QuantityStep: &p.QuantityStep, Status: p.Status, Latitude: &p.Latitude, Longitude: &p.Longitude,
+		}
+		if err := encoder.Encode(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+package catalog
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/LocalLink/internal/models"
+)
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name      string
+		format    string
+		input     string
+		wantRows  int
+		wantGiven map[string]bool // of the first row
+		wantLines []int           // of the rows, then of the row errors
+		wantErrs  []string        // a substring of each row error's message
+	}{
+		{
+			name:      "csv with every column",
+			format:    FormatCSV,
+			input:     "sku,name,description,price,quantity,unit,quantity_step,status,latitude,longitude\nEGG12,Eggs,Free range,3.5,30,each,1,published,53.8,-1.55\n",
+			wantRows:  1,
+			wantGiven: map[string]bool{"description": true, "quantity": true, "unit": true, "quantity_step": true, "status": true},
+			wantLines: []int{2},
+		},
+		{
+			name:      "csv with only the required columns",
+			format:    FormatCSV,
+			input:     "sku,name,price\nEGG12,Eggs,3.5\n",
+			wantRows:  1,
+			wantGiven: map[string]bool{},
+			wantLines: []int{2},
+		},
+		{
+			name:      "csv with empty optional cells",
+			format:    FormatCSV,
+			input:     "sku,name,price,quantity,unit\nEGG12,Eggs,3.5,,\n",
+			wantRows:  1,
+			wantGiven: map[string]bool{},
+			wantLines: []int{2},
+		},
+		{
+			name:      "csv header with byte order mark and odd case",
+			format:    FormatCSV,
+			input:     "\ufeffSKU, Name ,PRICE\nEGG12,Eggs,3.5\n",
+			wantRows:  1,
+			wantGiven: map[string]bool{},
+			wantLines: []int{2},
+		},
+		{
+			name:      "csv row without a price",
+			format:    FormatCSV,
+			input:     "sku,name,price\nEGG12,Eggs,\nMILK,Milk,1.2\n",
+			wantRows:  1,
+			wantLines: []int{3, 2},
+			wantErrs:  []string{"price is required"},
+		},
+		{
+			name:      "csv numbers that don't parse",
+			format:    FormatCSV,
+			input:     "sku,name,price,quantity\nEGG12,Eggs,cheap,lots\n",
+			wantLines: []int{2},
+			wantErrs:  []string{`price "cheap" is not a number`},
+		},
+		{
+			name:      "csv parse error keeps its line",
+			format:    FormatCSV,
+			input:     "sku,name,price\nEGG12,Eggs,3.5\nMILK,Mi\"lk,1.2\nHONEY,Honey,6\n",
+			wantRows:  2,
+			wantLines: []int{2, 4, 3},
+			wantErrs:  []string{"bare"},
+		},
+		{
+			name:      "jsonl with every field",
+			format:    FormatJSONL,
+			input:     `{"sku":"EGG12","name":"Eggs","description":"Free range","price":3.5,"quantity":30,"unit":"each","quantityStep":1,"status":"published","latitude":53.8,"longitude":-1.55}` + "\n",
+			wantRows:  1,
+			wantGiven: map[string]bool{"description": true, "quantity": true, "unit": true, "quantity_step": true, "status": true},
+			wantLines: []int{1},
+		},
+		{
+			name:      "jsonl with only the required fields",
+			format:    FormatJSONL,
+			input:     `{"sku":"EGG12","name":"Eggs","price":3.5}` + "\n",
+			wantRows:  1,
+			wantGiven: map[string]bool{},
+			wantLines: []int{1},
+		},
+		{
+			name:      "jsonl row without a price",
+			format:    FormatJSONL,
+			input:     `{"sku":"EGG12","name":"Eggs","quantity":30}` + "\n\n" + `{"sku":"MILK","name":"Milk","price":1.2}` + "\n",
+			wantRows:  1,
+			wantLines: []int{3, 1},
+			wantErrs:  []string{"price is required"},
+		},
+		{
+			name:      "jsonl invalid and unknown fields",
+			format:    FormatJSONL,
+			input:     "{\"sku\":\n" + `{"sku":"EGG12","name":"Eggs","price":3.5,"colour":"brown"}` + "\n",
+			wantLines: []int{1, 2},
+			wantErrs:  []string{"invalid JSON", "unknown field"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, rowErrors, err := Read(strings.NewReader(tt.input), tt.format)
+			if err != nil {
+				t.Fatalf("Read error: %v", err)
+			}
+			if len(rows) != tt.wantRows || len(rowErrors) != len(tt.wantErrs) {
+				t.Fatalf("Read = %d rows, %d errors %v; want %d rows, %d errors", len(rows), len(rowErrors), rowErrors, tt.wantRows, len(tt.wantErrs))
+			}
+			var lines []int
+			for _, row := range rows {
+				lines = append(lines, row.Line)
+			}
+			for i, rowErr := range rowErrors {
+				lines = append(lines, rowErr.Line)
+				if !strings.Contains(rowErr.Message, tt.wantErrs[i]) {
+					t.Errorf("row error %d = %q, want it to mention %q", i, rowErr.Message, tt.wantErrs[i])
+				}
+			}
+			if !reflect.DeepEqual(lines, tt.wantLines) {
+				t.Errorf("lines = %v, want %v", lines, tt.wantLines)
+			}
+			if tt.wantGiven != nil {
+				given := make(map[string]bool)
+				for name, set := range rows[0].Given {
+					if set {
+						given[name] = true
+					}
+				}
+				if !reflect.DeepEqual(given, tt.wantGiven) {
+					t.Errorf("Given = %v, want %v", given, tt.wantGiven)
+				}
+			}
+		})
+	}
+}
+
+func TestReadFileErrors(t *testing.T) {
+	tooMany := func(header, format string) string {
+		var b strings.Builder
+		b.WriteString(header)
+		for i := 0; i <= MaxRows; i++ {
+			fmt.Fprintf(&b, format, i)
+		}
+		return b.String()
+	}
+	tests := []struct {
+		name   string
+		format string
+		input  string
+	}{
+		{"unknown format", "xlsx", "sku,name,price\n"},
+		{"empty csv", FormatCSV, ""},
+		{"csv header missing price", FormatCSV, "sku,name\nEGG12,Eggs\n"},
+		{"csv over the row limit", FormatCSV, tooMany("sku,name,price\n", "SKU%d,Eggs,1\n")},
+		{"jsonl over the row limit", FormatJSONL, tooMany("", `{"sku":"SKU%d","name":"Eggs","price":1}`+"\n")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := Read(strings.NewReader(tt.input), tt.format); err == nil {
+				t.Error("Read succeeded")
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	row := func(line int, p models.Product, given ...string) Row {
+		r := Row{Line: line, Product: p, Given: make(map[string]bool)}
+		for _, name := range given {
+			r.Given[name] = true
+		}
+		return r
+	}
+	eggs := models.Product{SKU: "EGG12", Name: "Eggs", Price: 3.5}
+	tests := []struct {
+		name     string
+		rows     []Row
+		wantErrs []string
+	}{
+		{"valid", []Row{row(2, eggs)}, nil},
+		{"missing sku and name", []Row{row(2, models.Product{Price: 1})}, []string{"sku is required; name is required"}},
+		{"repeated sku", []Row{row(2, eggs), row(5, eggs)}, []string{"sku is repeated from line 2"}},
+		{"negative price and quantity", []Row{row(2, models.Product{SKU: "A", Name: "A", Price: -1, Quantity: -2})}, []string{"price cannot be negative; quantity cannot be negative"}},
+		{"unknown unit and status", []Row{row(2, models.Product{SKU: "A", Name: "A", Unit: "bushel", Status: "sold"})}, []string{`unknown unit "bushel"; unknown status "sold"`}},
+		{"quantity step given as zero", []Row{row(2, eggs, "quantity_step")}, []string{"quantity_step must be positive"}},
+		{"quantity step left out", []Row{row(2, eggs)}, nil},
+		{"location out of range", []Row{{Line: 2, Product: models.Product{SKU: "A", Name: "A", Latitude: 91}, HasLocation: true}}, []string{"latitude or longitude is out of range"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rowErrors := Validate(tt.rows)
+			var got []string
+			for _, rowErr := range rowErrors {
+				got = append(got, rowErr.Message)
+			}
+			if !reflect.DeepEqual(got, tt.wantErrs) {
+				t.Errorf("Validate = %q, want %q", got, tt.wantErrs)
+			}
+		})
+	}
+}
+
+func TestWriteReadRoundTrip(t *testing.T) {
+	products := []models.Product{
+		{SKU: "EGG12", Name: "Eggs", Description: "Free range, \"large\"", Price: 3.5, Quantity: 30, Unit: "each",
+			QuantityStep: 1, Status: models.ProductStatusPublished, Latitude: 53.8, Longitude: -1.55},
+		{SKU: "FLOUR", Name: "Flour", Description: "Stoneground\nwholemeal", Price: 2.25, Quantity: 12.5, Unit: "kg",
+			QuantityStep: 0.5, Status: models.ProductStatusPaused, Latitude: -33.87, Longitude: 151.21},
+	}
+	for _, format := range []string{FormatCSV, FormatJSONL} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Write(&buf, format, products); err != nil {
+				t.Fatalf("Write error: %v", err)
+			}
+			rows, rowErrors, err := Read(&buf, format)
+			if err != nil || len(rowErrors) > 0 {
+				t.Fatalf("Read error: %v %v", err, rowErrors)
+			}
+			if len(rows) != len(products) {
+				t.Fatalf("Read %d rows, want %d", len(rows), len(products))
+			}
+			if rowErrors := Validate(rows); len(rowErrors) > 0 {
+				t.Errorf("Validate: %v", rowErrors)
+			}
+			for i, row := range rows {
+				if !row.HasLocation {
+					t.Errorf("row %d: HasLocation is false", i)
+				}
+				if !reflect.DeepEqual(row.Product, products[i]) {
+					t.Errorf("row %d = %+v, want %+v", i, row.Product, products[i])
+				}
+			}
+		})
+	}
+}
@@ -3,6 +3,9 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -10,6 +13,47 @@ import (
 type Config struct {
 	DatabaseURL string
 	JWTSecret   string
+	// JWTKeysDir holds <kid>.pem signing and verification keys; see auth.KeySet.
+	JWTKeysDir     string
+	JWTActiveKeyID string
+	JWTIssuer      string
+	JWTAudience    string
+	// JWTLegacyCutoff is when API tokens from before issuer and audience checks stop
+	// being accepted. Zero means one token lifetime after startup.
+	JWTLegacyCutoff time.Time
+	// OIDCProviders are keyed by the name used in /auth/oidc/{provider} routes.
+	OIDCProviders map[string]OIDCProvider
+	// OIDCSuccessRedirectURL, if set, receives the issued token in its fragment after
+	// a social login instead of the callback responding with JSON.
+	OIDCSuccessRedirectURL string
+	// AccountDeletionGracePeriod is how long a deletion request can be cancelled
+	// before the account's personal data is erased.
+	AccountDeletionGracePeriod time.Duration
+	// UploadDir is where the local blob store keeps uploaded files.
+	UploadDir           string
+	MaxImageUploadBytes int64
+	MaxImagesPerProduct int
+	// ReservationTTL is how long checkout holds stock before it is released.
+	ReservationTTL time.Duration
+	// GazetteerPath is a CSV of postcodes or place names and their coordinates used
+	// to geocode addresses. Geocoding is off when it is empty.
+	GazetteerPath string
+	// MaxSearchRadius caps the radius, in metres, of nearby searches.
+	MaxSearchRadius int
+	// SMTPAddr (host:port) is the relay for email notifications. Without it, emails
+	// are only logged.
+	SMTPAddr     string
+	SMTPUsername string
+	SMTPPassword string
+	MailFrom     string
+}
+
+type OIDCProvider struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
 }
 
 func Load() *Config {
@@ -18,7 +62,81 @@ func Load() *Config {
 	}
 
 	return &Config{
-		DatabaseURL: os.Getenv("DATABASE_URL"),
-		JWTSecret:   os.Getenv("JWT_SECRET"),
+		DatabaseURL:                os.Getenv("DATABASE_URL"),
+		JWTSecret:                  os.Getenv("JWT_SECRET"),
+		JWTKeysDir:                 os.Getenv("JWT_KEYS_DIR"),
+		JWTActiveKeyID:             os.Getenv("JWT_ACTIVE_KEY_ID"),
+		JWTIssuer:                  getEnv("JWT_ISSUER", "locallink"),
+		JWTAudience:                getEnv("JWT_AUDIENCE", "locallink-api"),
+		JWTLegacyCutoff:            getEnvTime("JWT_LEGACY_CUTOFF"),
+		OIDCProviders:              loadOIDCProviders(),
+		OIDCSuccessRedirectURL:     os.Getenv("OIDC_SUCCESS_REDIRECT_URL"),
+		AccountDeletionGracePeriod: time.Duration(getEnvInt("ACCOUNT_DELETION_GRACE_DAYS", 30)) * 24 * time.Hour,
+		UploadDir:                  getEnv("UPLOAD_DIR", "./uploads"),
+		MaxImageUploadBytes:        int64(getEnvInt("MAX_IMAGE_UPLOAD_MB", 10)) << 20,
+		MaxImagesPerProduct:        getEnvInt("MAX_IMAGES_PER_PRODUCT", 10),
+		ReservationTTL:             time.Duration(getEnvInt("RESERVATION_TTL_MINUTES", 15)) * time.Minute,
+		GazetteerPath:              os.Getenv("GAZETTEER_PATH"),
+		MaxSearchRadius:            getEnvInt("MAX_SEARCH_RADIUS_METERS", 50000),
+		SMTPAddr:                   os.Getenv("SMTP_ADDR"),
+		SMTPUsername:               os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:               os.Getenv("SMTP_PASSWORD"),
+		MailFrom:                   getEnv("MAIL_FROM", "LocalLink <no-reply@locallink.app>"),
+	}
+}
+
+// loadOIDCProviders reads OIDC_PROVIDERS (e.g. "google,local") and, for each name,
+// OIDC_<NAME>_ISSUER, _CLIENT_ID, _CLIENT_SECRET, _REDIRECT_URL and optional _SCOPES.
+func loadOIDCProviders() map[string]OIDCProvider {
+	providers := make(map[string]OIDCProvider)
+	for _, name := range splitList(os.Getenv("OIDC_PROVIDERS")) {
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		scopes := splitList(os.Getenv(prefix + "SCOPES"))
+		if len(scopes) == 0 {
+			scopes = []string{"openid", "email", "profile"}
+		}
+		providers[name] = OIDCProvider{
+			IssuerURL:    os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+			Scopes:       scopes,
+		}
+	}
+	return providers
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func getEnvInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// getEnvTime reads an RFC 3339 timestamp, returning the zero time if it is unset or
+// malformed.
+func getEnvTime(key string) time.Time {
+	value, err := time.Parse(time.RFC3339, os.Getenv(key))
+	if err != nil {
+		return time.Time{}
 	}
-}
\ No newline at end of file
+	return value
+}
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
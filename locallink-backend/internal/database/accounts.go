@@ -0,0 +1,109 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/LocalLink/internal/models"
+)
+
+// Account Data Methods
+func (s *Store) GetIdentitiesForUser(ctx context.Context, userID int) ([]models.UserIdentity, error) {
+	query := `SELECT provider, subject, email, created_at FROM user_identities WHERE user_id = $1 ORDER BY created_at`
+	rows, err := s.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var identities []models.UserIdentity
+	for rows.Next() {
+		var i models.UserIdentity
+		if err := rows.Scan(&i.Provider, &i.Subject, &i.Email, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		identities = append(identities, i)
+	}
+	return identities, nil
+}
+
+func (s *Store) GetReviewsByUser(ctx context.Context, userID int) ([]models.Review, error) {
+	query := `SELECT id, product_id, user_id, rating, comment, created_at FROM reviews WHERE user_id = $1 ORDER BY created_at DESC`
+	rows, err := s.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reviews []models.Review
+	for rows.Next() {
+		var r models.Review
+		if err := rows.Scan(&r.ID, &r.ProductID, &r.UserID, &r.Rating, &r.Comment, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, r)
+	}
+	return reviews, nil
+}
+
+func (s *Store) ScheduleUserDeletion(ctx context.Context, userID int, at time.Time) error {
+	_, err := s.db.Exec(ctx, `UPDATE users SET deletion_scheduled_for = $1 WHERE id = $2 AND anonymized_at IS NULL`, at, userID)
+	return err
+}
+
+func (s *Store) CancelUserDeletion(ctx context.Context, userID int) error {
+	_, err := s.db.Exec(ctx, `UPDATE users SET deletion_scheduled_for = NULL WHERE id = $1 AND anonymized_at IS NULL`, userID)
+	return err
+}
+
+// GetDueDeletions lists the accounts whose deletion grace period has ended and that
+// have not been anonymized yet.
+func (s *Store) GetDueDeletions(ctx context.Context) ([]int, error) {
+	rows, err := s.db.Query(ctx, `SELECT id FROM users WHERE deletion_scheduled_for <= NOW() AND anonymized_at IS NULL ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, id)
+	}
+	return userIDs, rows.Err()
+}
+
+// AnonymizeUser erases an account's personal data and takes down its listings. The
+// users row is kept, scrubbed, so orders still reference a valid counterparty.
+func (s *Store) AnonymizeUser(ctx context.Context, userID int) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	statements := []string{
+		`UPDATE users SET name = 'Deleted user', email = 'deleted-' || id || '@deleted.invalid', password_hash = '',
+		 totp_secret = NULL, mfa_enabled = FALSE, default_location = NULL, default_location_label = NULL, anonymized_at = NOW() WHERE id = $1`,
+		`UPDATE reviews SET comment = '' WHERE user_id = $1`,
+		`DELETE FROM user_identities WHERE user_id = $1`,
+		`DELETE FROM api_keys WHERE user_id = $1`,
+		`DELETE FROM mfa_backup_codes WHERE user_id = $1`,
+		`UPDATE user_sessions SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`,
+		`DELETE FROM producer_profiles WHERE user_id = $1`,
+		`DELETE FROM saved_searches WHERE user_id = $1`,
+		`UPDATE subscriptions SET status = 'cancelled', cancelled_at = COALESCE(cancelled_at, NOW())
+		 WHERE status <> 'cancelled' AND (buyer_id = $1 OR plan_id IN (SELECT id FROM subscription_plans WHERE producer_id = $1))`,
+		`UPDATE subscription_plans SET active = FALSE WHERE producer_id = $1`,
+		`UPDATE products SET status = 'archived', archived_at = COALESCE(archived_at, NOW()) WHERE producer_id = $1`,
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(ctx, statement, userID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,57 @@
+package database
+
+import (
+	"context"
+
+	"github.com/LocalLink/internal/models"
+)
+
+// API Key Methods
+func (s *Store) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	query := `INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes) VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`
+	return s.db.QueryRow(ctx, query, key.UserID, key.Name, key.Prefix, key.KeyHash, key.Scopes).Scan(&key.ID, &key.CreatedAt)
+}
+
+func (s *Store) GetAPIKeysForUser(ctx context.Context, userID int) ([]models.APIKey, error) {
+	query := `SELECT id, user_id, name, prefix, scopes, created_at, last_used_at, revoked_at FROM api_keys WHERE user_id = $1 ORDER BY created_at DESC`
+	rows, err := s.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []models.APIKey
+	for rows.Next() {
+		var k models.APIKey
+		if err := rows.Scan(&k.ID, &k.UserID, &k.Name, &k.Prefix, &k.Scopes, &k.CreatedAt, &k.LastUsedAt, &k.RevokedAt); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// GetActiveAPIKeyByHash returns an unrevoked key matching the hash.
+func (s *Store) GetActiveAPIKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	var k models.APIKey
+	query := `SELECT id, user_id, name, prefix, scopes, created_at, last_used_at, revoked_at FROM api_keys WHERE key_hash = $1 AND revoked_at IS NULL`
+	err := s.db.QueryRow(ctx, query, keyHash).Scan(&k.ID, &k.UserID, &k.Name, &k.Prefix, &k.Scopes, &k.CreatedAt, &k.LastUsedAt, &k.RevokedAt)
+	return &k, err
+}
+
+// TouchAPIKey records use of a key, writing at most once a minute per key.
+func (s *Store) TouchAPIKey(ctx context.Context, keyID int) error {
+	query := `UPDATE api_keys SET last_used_at = NOW() WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')`
+	_, err := s.db.Exec(ctx, query, keyID)
+	return err
+}
+
+// RevokeAPIKey revokes one of the user's keys and reports whether it existed.
+func (s *Store) RevokeAPIKey(ctx context.Context, userID, keyID int) (bool, error) {
+	query := `UPDATE api_keys SET revoked_at = NOW() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`
+	tag, err := s.db.Exec(ctx, query, keyID, userID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
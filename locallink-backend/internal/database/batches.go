@@ -0,0 +1,229 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/LocalLink/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrUntrackedStock is returned when the first batch is added to a product that still
+// has stock outside batches, which would have no best-before date.
+var ErrUntrackedStock = errors.New("product has stock that is not in a batch")
+
+// Product Batch Methods
+
+// CreateProductBatch adds a batch and its quantity to the product's stock. The first
+// batch switches the product to batch tracking for good.
+func (s *Store) CreateProductBatch(ctx context.Context, batch *models.ProductBatch) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var tracked bool
+	var stock float64
+	if err := tx.QueryRow(ctx, `SELECT batch_tracked, quantity FROM products WHERE id = $1 FOR UPDATE`, batch.ProductID).Scan(&tracked, &stock); err != nil {
+		return err
+	}
+	if !tracked && stock > 0 {
+		return ErrUntrackedStock
+	}
+	query := `INSERT INTO product_batches (product_id, quantity, harvested_on, best_before) VALUES ($1, $2, $3, $4)
+              RETURNING id, best_before - CURRENT_DATE, created_at`
+	err = tx.QueryRow(ctx, query, batch.ProductID, batch.Quantity, batch.HarvestedOn, batch.BestBefore).Scan(&batch.ID, &batch.DaysLeft, &batch.CreatedAt)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE products SET quantity = quantity + $1, batch_tracked = TRUE WHERE id = $2`, batch.Quantity, batch.ProductID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (s *Store) GetProductBatch(ctx context.Context, batchID int) (*models.ProductBatch, error) {
+	var b models.ProductBatch
+	query := `SELECT id, product_id, quantity, harvested_on, best_before, best_before - CURRENT_DATE, created_at FROM product_batches WHERE id = $1`
+	err := s.db.QueryRow(ctx, query, batchID).Scan(&b.ID, &b.ProductID, &b.Quantity, &b.HarvestedOn, &b.BestBefore, &b.DaysLeft, &b.CreatedAt)
+	return &b, err
+}
+
+// DeleteProductBatch removes a batch and whatever is left of it from the product's stock.
+func (s *Store) DeleteProductBatch(ctx context.Context, batchID int) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var productID int
+	var remaining float64
+	if err := tx.QueryRow(ctx, `DELETE FROM product_batches WHERE id = $1 RETURNING product_id, quantity`, batchID).Scan(&productID, &remaining); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE products SET quantity = GREATEST(quantity - $1, 0) WHERE id = $2`, remaining, productID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ExpireBatches withdraws batches past their best-before date from sale. It returns
+// the number of products whose stock went down.
+func (s *Store) ExpireBatches(ctx context.Context) (int, error) {
+	query := `WITH expired AS (
+                  UPDATE product_batches SET expired_quantity = quantity, quantity = 0
+                  WHERE best_before < CURRENT_DATE AND quantity > 0
+                  RETURNING product_id, expired_quantity
+              )
+              UPDATE products p SET quantity = GREATEST(p.quantity - e.total, 0)
+              FROM (SELECT product_id, SUM(expired_quantity) AS total FROM expired GROUP BY product_id) e
+              WHERE p.id = e.product_id`
+	tag, err := s.db.Exec(ctx, query)
+	return int(tag.RowsAffected()), err
+}
+
+// attachBatches loads the unexpired batches of each product in one query and works
+// out the markdown currently applied to the oldest of them.
+func (s *Store) attachBatches(ctx context.Context, products []models.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+	ids := make([]int, len(products))
+	for i, p := range products {
+		ids[i] = p.ID
+	}
+	query := `SELECT id, product_id, quantity, harvested_on, best_before, best_before - CURRENT_DATE, created_at FROM product_batches
+              WHERE product_id = ANY($1) AND quantity > 0 AND best_before >= CURRENT_DATE ORDER BY product_id, best_before, id`
+	rows, err := s.db.Query(ctx, query, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	batchesByProduct := make(map[int][]models.ProductBatch)
+	for rows.Next() {
+		var b models.ProductBatch
+		if err := rows.Scan(&b.ID, &b.ProductID, &b.Quantity, &b.HarvestedOn, &b.BestBefore, &b.DaysLeft, &b.CreatedAt); err != nil {
+			return err
+		}
+		batchesByProduct[b.ProductID] = append(batchesByProduct[b.ProductID], b)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for i := range products {
+		p := &products[i]
+		p.Batches = batchesByProduct[p.ID]
+		if len(p.Batches) == 0 {
+			continue
+		}
+		if percent := markdownPercent(p.Markdowns, p.Batches[0].DaysLeft); percent > 0 {
+			salePrice := markedDown(p.Price, percent)
+			p.DiscountPercent, p.SalePrice = percent, &salePrice
+		}
+	}
+	return nil
+}
+
+// takeFromBatches consumes quantity from the product's unexpired batches, oldest
+// best-before first, and returns the allocations and the average unit price after
+// markdowns. Products not tracked in batches return no allocations and the base price.
+func takeFromBatches(ctx context.Context, tx pgx.Tx, productID int, quantity, price float64, markdowns []models.Markdown) ([]models.BatchAllocation, float64, error) {
+	var tracked bool
+	if err := tx.QueryRow(ctx, `SELECT batch_tracked FROM products WHERE id = $1`, productID).Scan(&tracked); err != nil {
+		return nil, 0, err
+	}
+	if !tracked {
+		return nil, price, nil
+	}
+
+	query := `SELECT id, quantity, best_before, best_before - CURRENT_DATE FROM product_batches
+              WHERE product_id = $1 AND quantity > 0 AND best_before >= CURRENT_DATE ORDER BY best_before, id FOR UPDATE`
+	rows, err := tx.Query(ctx, query, productID)
+	if err != nil {
+		return nil, 0, err
+	}
+	type batch struct {
+		allocation models.BatchAllocation
+		available  float64
+		daysLeft   int
+	}
+	var batches []batch
+	for rows.Next() {
+		var b batch
+		if err := rows.Scan(&b.allocation.BatchID, &b.available, &b.allocation.BestBefore, &b.daysLeft); err != nil {
+			rows.Close()
+			return nil, 0, err
+		}
+		batches = append(batches, b)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	var allocations []models.BatchAllocation
+	var total float64
+	remaining := quantity
+	for _, b := range batches {
+		if remaining <= 0 {
+			break
+		}
+		take := math.Min(b.available, remaining)
+		if _, err := tx.Exec(ctx, `UPDATE product_batches SET quantity = quantity - $1 WHERE id = $2`, take, b.allocation.BatchID); err != nil {
+			return nil, 0, err
+		}
+		b.allocation.Quantity = take
+		allocations = append(allocations, b.allocation)
+		total += take * markedDown(price, markdownPercent(markdowns, b.daysLeft))
+		remaining -= take
+	}
+	if remaining > 1e-9 {
+		return nil, 0, fmt.Errorf("%w: not enough fresh stock for product ID %d", ErrInsufficientStock, productID)
+	}
+	return allocations, math.Round(total/quantity*100) / 100, nil
+}
+
+// restoreBatches puts allocated stock back into its batches and returns how much it
+// put back. Batches that have been deleted since are skipped.
+func restoreBatches(ctx context.Context, tx pgx.Tx, allocations []models.BatchAllocation) (float64, error) {
+	var restored float64
+	for _, a := range allocations {
+		tag, err := tx.Exec(ctx, `UPDATE product_batches SET quantity = quantity + $1 WHERE id = $2`, a.Quantity, a.BatchID)
+		if err != nil {
+			return restored, err
+		}
+		if tag.RowsAffected() > 0 {
+			restored += a.Quantity
+		}
+	}
+	return restored, nil
+}
+
+// markdownPercent returns the largest discount whose window the batch has entered.
+func markdownPercent(markdowns []models.Markdown, daysLeft int) float64 {
+	var percent float64
+	for _, m := range markdowns {
+		if daysLeft <= m.DaysBefore && m.PercentOff > percent {
+			percent = m.PercentOff
+		}
+	}
+	return percent
+}
+
+func markedDown(price, percent float64) float64 {
+	return math.Round(price*(100-percent)) / 100
+}
+
+// batchesParam stores items without batch allocations as NULL rather than a JSON null.
+func batchesParam(allocations []models.BatchAllocation) any {
+	if len(allocations) == 0 {
+		return nil
+	}
+	return allocations
+}
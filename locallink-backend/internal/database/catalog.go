@@ -0,0 +1,85 @@
+package database
+
+import (
+	"context"
+	"errors"
+
+	"github.com/LocalLink/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Catalogue Methods
+func (s *Store) GetProductsByProducer(ctx context.Context, producerID int, includeArchived bool) ([]models.Product, error) {
+	query := `SELECT ` + productColumns + ` FROM products WHERE producer_id = $1 AND ($2 OR status <> 'archived') ORDER BY sku NULLS LAST, id`
+	rows, err := s.db.Query(ctx, query, producerID, includeArchived)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var products []models.Product
+	for rows.Next() {
+		var p models.Product
+		if err := scanProduct(rows, &p); err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+	}
+	return products, rows.Err()
+}
+
+// ImportProducts upserts the producer's products by SKU in one transaction, so an
+// import either applies completely or not at all. Variants and images are untouched,
+// as is the quantity of products tracked in batches.
+func (s *Store) ImportProducts(ctx context.Context, producerID int, imports []models.ProductImport) (created, updated int, err error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	for i := range imports {
+		p := &imports[i].Product
+		p.ProducerID = producerID
+		var oldPrice float64
+		err := tx.QueryRow(ctx, `SELECT id, price FROM products WHERE producer_id = $1 AND sku = $2 FOR UPDATE`, producerID, p.SKU).Scan(&p.ID, &oldPrice)
+		if errors.Is(err, pgx.ErrNoRows) {
+			if err := insertProduct(ctx, tx, p); err != nil {
+				return 0, 0, err
+			}
+			created++
+			continue
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+
+		input := imports[i].Update
+		query := `UPDATE products SET name = COALESCE($1, name), description = COALESCE($2, description), price = COALESCE($3, price),
+                  quantity = CASE WHEN batch_tracked THEN quantity ELSE COALESCE($4, quantity) END,
+                  unit = COALESCE($5, unit), quantity_step = COALESCE($6, quantity_step), status = COALESCE($7, status),
+                  location = CASE WHEN $8 THEN ST_MakePoint($9, $10)::geography ELSE location END,
+                  archived_at = CASE WHEN COALESCE($7, status) = 'archived' THEN COALESCE(archived_at, NOW()) END WHERE id = $11`
+		_, err = tx.Exec(ctx, query, input.Name, input.Description, input.Price, input.Quantity, input.Unit, input.QuantityStep, input.Status,
+			imports[i].HasLocation, p.Longitude, p.Latitude, p.ID)
+		if err != nil {
+			return 0, 0, err
+		}
+		if input.Price != nil && *input.Price != oldPrice {
+			if err := recordPrice(ctx, tx, p.ID, nil, *input.Price); err != nil {
+				return 0, 0, err
+			}
+		}
+		updated++
+	}
+	return created, updated, tx.Commit(ctx)
+}
+
+// IsUniqueViolation reports whether err came from a unique constraint, such as a
+// producer reusing a SKU.
+func IsUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
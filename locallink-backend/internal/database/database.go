@@ -2,14 +2,20 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 
 	"github.com/LocalLink/internal/models"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// ErrOrderCancelled is returned when changing the status of a cancelled order. Its
+// stock and time slot have been given back, so it cannot be reopened.
+var ErrOrderCancelled = errors.New("cancelled orders cannot be reopened")
+
 type Store struct {
 	db *pgxpool.Pool
 }
@@ -38,18 +44,40 @@ func (s *Store) CreateUser(ctx context.Context, user *models.User) error {
 
 func (s *Store) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
 	var user models.User
-	query := `SELECT id, name, email, password_hash, role, created_at FROM users WHERE email = $1`
-	err := s.db.QueryRow(ctx, query, email).Scan(&user.ID, &user.Name, &user.Email, &user.PasswordHash, &user.Role, &user.CreatedAt)
+	query := `SELECT id, name, email, password_hash, role, mfa_enabled, COALESCE(totp_secret, ''), created_at, deletion_scheduled_for FROM users WHERE email = $1`
+	err := s.db.QueryRow(ctx, query, email).Scan(&user.ID, &user.Name, &user.Email, &user.PasswordHash, &user.Role, &user.MFAEnabled, &user.TOTPSecret, &user.CreatedAt, &user.DeletionScheduledFor)
 	return &user, err
 }
 
 func (s *Store) GetUserByID(ctx context.Context, id int) (*models.User, error) {
 	var user models.User
-	query := `SELECT id, name, email, role, created_at FROM users WHERE id = $1`
-	err := s.db.QueryRow(ctx, query, id).Scan(&user.ID, &user.Name, &user.Email, &user.Role, &user.CreatedAt)
+	var label *string
+	var lat, lon *float64
+	query := `SELECT id, name, email, role, mfa_enabled, COALESCE(totp_secret, ''), created_at, deletion_scheduled_for,
+              default_location_label, ST_Y(default_location::geometry), ST_X(default_location::geometry) FROM users WHERE id = $1`
+	err := s.db.QueryRow(ctx, query, id).Scan(&user.ID, &user.Name, &user.Email, &user.Role, &user.MFAEnabled, &user.TOTPSecret, &user.CreatedAt, &user.DeletionScheduledFor,
+		&label, &lat, &lon)
+	if err == nil && lat != nil && lon != nil {
+		user.DefaultLocation = &models.Location{Latitude: *lat, Longitude: *lon}
+		if label != nil {
+			user.DefaultLocation.Label = *label
+		}
+	}
 	return &user, err
 }
 
+// SetDefaultLocation saves the point used for the user's searches without coordinates;
+// nil clears it.
+func (s *Store) SetDefaultLocation(ctx context.Context, userID int, loc *models.Location) error {
+	if loc == nil {
+		_, err := s.db.Exec(ctx, `UPDATE users SET default_location = NULL, default_location_label = NULL WHERE id = $1`, userID)
+		return err
+	}
+	query := `UPDATE users SET default_location = ST_MakePoint($1, $2)::geography, default_location_label = $3 WHERE id = $4`
+	_, err := s.db.Exec(ctx, query, loc.Longitude, loc.Latitude, loc.Label, userID)
+	return err
+}
+
 func (s *Store) UpdateUser(ctx context.Context, userID int, input models.UpdateUserInput) (*models.User, error) {
 	if input.Name != nil {
 		query := `UPDATE users SET name = $1 WHERE id = $2`
@@ -62,15 +90,73 @@ func (s *Store) UpdateUser(ctx context.Context, userID int, input models.UpdateU
 }
 
 // Product Methods
+const productColumns = `id, producer_id, COALESCE(sku, ''), name, description, category, price, quantity, unit, quantity_step, status, markdowns,
+                  available_from, preorder_limit, preordered_quantity, (available_from IS NOT NULL AND released_at IS NULL),
+                  batch_tracked, ST_Y(location::geometry), ST_X(location::geometry), created_at`
+
+// scanProduct scans productColumns, followed by any extra selected columns.
+func scanProduct(row pgx.Row, p *models.Product, extra ...any) error {
+	dest := []any{&p.ID, &p.ProducerID, &p.SKU, &p.Name, &p.Description, &p.Category, &p.Price, &p.Quantity, &p.Unit, &p.QuantityStep, &p.Status, &p.Markdowns,
+		&p.AvailableFrom, &p.PreorderLimit, &p.PreorderedQuantity, &p.Preorder, &p.BatchTracked, &p.Latitude, &p.Longitude, &p.CreatedAt}
+	return row.Scan(append(dest, extra...)...)
+}
+
 func (s *Store) CreateProduct(ctx context.Context, product *models.Product) error {
-	query := `INSERT INTO products (producer_id, name, description, price, quantity, location) 
-              VALUES ($1, $2, $3, $4, $5, ST_MakePoint($6, $7)::geography) RETURNING id, created_at`
-	return s.db.QueryRow(ctx, query, product.ProducerID, product.Name, product.Description, product.Price, product.Quantity, product.Longitude, product.Latitude).Scan(&product.ID, &product.CreatedAt)
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := insertProduct(ctx, tx, product); err != nil {
+		return err
+	}
+	for i := range product.Variants {
+		variant := &product.Variants[i]
+		variant.ProductID = product.ID
+		if err := insertVariant(ctx, tx, variant); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func insertProduct(ctx context.Context, tx pgx.Tx, product *models.Product) error {
+	if product.Markdowns == nil {
+		product.Markdowns = []models.Markdown{}
+	}
+	query := `INSERT INTO products (producer_id, sku, name, description, price, quantity, unit, quantity_step, status, markdowns, location, category) 
+              VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, $7, $8, $9, $10, ST_MakePoint($11, $12)::geography, $13) RETURNING id, created_at`
+	err := tx.QueryRow(ctx, query, product.ProducerID, product.SKU, product.Name, product.Description, product.Price, product.Quantity, product.Unit, product.QuantityStep, product.Status, product.Markdowns, product.Longitude, product.Latitude, product.Category).Scan(&product.ID, &product.CreatedAt)
+	if err != nil {
+		return err
+	}
+	return recordPrice(ctx, tx, product.ID, nil, product.Price)
+}
+
+// availableProduct matches products (aliased p) buyers can order now: published, with
+// stock or a variant in stock, or open for pre-order.
+const availableProduct = `p.status = 'published'
+              AND (p.quantity > 0 OR EXISTS (SELECT 1 FROM product_variants v WHERE v.product_id = p.id AND v.quantity > 0)
+                   OR (p.available_from IS NOT NULL AND p.released_at IS NULL AND p.preordered_quantity < p.preorder_limit))`
+
+// nearbySortOrder maps a nearby search's sort option to its ORDER BY clause.
+var nearbySortOrder = map[string]string{
+	models.SortDistance: `location <-> ST_MakePoint($1, $2)::geography, id`,
+	models.SortPrice:    `price, id`,
+	models.SortNewest:   `created_at DESC, id DESC`,
 }
 
-func (s *Store) GetProductsNearby(ctx context.Context, lat, lon float64, radius int) ([]models.Product, error) {
-	query := `SELECT id, producer_id, name, description, price, quantity, ST_Y(location::geometry), ST_X(location::geometry), created_at
-              FROM products WHERE ST_DWithin(location, ST_MakePoint($1, $2)::geography, $3)`
+// GetProductsNearby lists published, available products within radius metres, each
+// with its distance from the search point, ordered by sortBy (distance by default).
+func (s *Store) GetProductsNearby(ctx context.Context, lat, lon float64, radius int, sortBy string) ([]models.Product, error) {
+	orderBy, ok := nearbySortOrder[sortBy]
+	if !ok {
+		orderBy = nearbySortOrder[models.SortDistance]
+	}
+	query := `SELECT ` + productColumns + `, ST_Distance(location, ST_MakePoint($1, $2)::geography)
+              FROM products p WHERE ST_DWithin(location, ST_MakePoint($1, $2)::geography, $3) AND ` + availableProduct + `
+              ORDER BY ` + orderBy
 	rows, err := s.db.Query(ctx, query, lon, lat, radius)
 	if err != nil {
 		return nil, err
@@ -79,32 +165,80 @@ func (s *Store) GetProductsNearby(ctx context.Context, lat, lon float64, radius
 	var products []models.Product
 	for rows.Next() {
 		var p models.Product
-		if err := rows.Scan(&p.ID, &p.ProducerID, &p.Name, &p.Description, &p.Price, &p.Quantity, &p.Latitude, &p.Longitude, &p.CreatedAt); err != nil {
+		var distance float64
+		if err := scanProduct(rows, &p, &distance); err != nil {
 			return nil, err
 		}
+		p.DistanceMeters = &distance
 		products = append(products, p)
 	}
+	if err := s.attachImages(ctx, products); err != nil {
+		return nil, err
+	}
+	if err := s.attachVariants(ctx, products); err != nil {
+		return nil, err
+	}
+	if err := s.attachBatches(ctx, products); err != nil {
+		return nil, err
+	}
 	return products, nil
 }
 
 func (s *Store) GetProductByID(ctx context.Context, productID int) (*models.Product, error) {
 	var p models.Product
-	query := `SELECT id, producer_id, name, description, price, quantity, ST_Y(location::geometry), ST_X(location::geometry), created_at FROM products WHERE id = $1`
-	err := s.db.QueryRow(ctx, query, productID).Scan(&p.ID, &p.ProducerID, &p.Name, &p.Description, &p.Price, &p.Quantity, &p.Latitude, &p.Longitude, &p.CreatedAt)
-	return &p, err
+	query := `SELECT ` + productColumns + ` FROM products WHERE id = $1`
+	if err := scanProduct(s.db.QueryRow(ctx, query, productID), &p); err != nil {
+		return &p, err
+	}
+	products := []models.Product{p}
+	if err := s.attachImages(ctx, products); err != nil {
+		return &p, err
+	}
+	if err := s.attachVariants(ctx, products); err != nil {
+		return &p, err
+	}
+	err := s.attachBatches(ctx, products)
+	return &products[0], err
 }
 
+// UpdateProduct applies the changes and, when the price changes, records the new price
+// in the product's price history.
 func (s *Store) UpdateProduct(ctx context.Context, productID int, input models.UpdateProductInput) (*models.Product, error) {
-	query := `UPDATE products SET name = COALESCE($1, name), description = COALESCE($2, description), price = COALESCE($3, price), quantity = COALESCE($4, quantity) WHERE id = $5`
-	_, err := s.db.Exec(ctx, query, input.Name, input.Description, input.Price, input.Quantity, productID)
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var oldPrice float64
+	if err := tx.QueryRow(ctx, `SELECT price FROM products WHERE id = $1 FOR UPDATE`, productID).Scan(&oldPrice); err != nil {
+		return nil, err
+	}
+	query := `UPDATE products SET name = COALESCE($1, name), description = COALESCE($2, description), price = COALESCE($3, price), quantity = COALESCE($4, quantity),
+              unit = COALESCE($5, unit), quantity_step = COALESCE($6, quantity_step), status = COALESCE($7, status),
+              archived_at = CASE WHEN COALESCE($7, status) = 'archived' THEN COALESCE(archived_at, NOW()) END,
+              sku = CASE WHEN $8::text IS NULL THEN sku ELSE NULLIF($8, '') END, markdowns = COALESCE($9, markdowns), category = COALESCE($10, category),
+              location = CASE WHEN $12::float8 IS NULL THEN location ELSE ST_MakePoint($13, $12)::geography END WHERE id = $11`
+	_, err = tx.Exec(ctx, query, input.Name, input.Description, input.Price, input.Quantity, input.Unit, input.QuantityStep, input.Status, input.SKU, input.Markdowns, input.Category, productID,
+		input.Latitude, input.Longitude)
 	if err != nil {
 		return nil, err
 	}
+	if input.Price != nil && *input.Price != oldPrice {
+		if err := recordPrice(ctx, tx, productID, nil, *input.Price); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
 	return s.GetProductByID(ctx, productID)
 }
 
-func (s *Store) DeleteProduct(ctx context.Context, productID int) error {
-	_, err := s.db.Exec(ctx, `DELETE FROM products WHERE id = $1`, productID)
+// ArchiveProduct takes a product off sale for good. The row is kept so order items
+// that reference it still resolve.
+func (s *Store) ArchiveProduct(ctx context.Context, productID int) error {
+	_, err := s.db.Exec(ctx, `UPDATE products SET status = 'archived', archived_at = COALESCE(archived_at, NOW()) WHERE id = $1`, productID)
 	return err
 }
 
@@ -116,58 +250,104 @@ func (s *Store) CreateOrder(ctx context.Context, input models.CreateOrderInput,
 	}
 	defer tx.Rollback(ctx)
 
+	orderID, err := createOrder(ctx, tx, input, buyerID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return s.GetOrderByID(ctx, orderID)
+}
+
+func createOrder(ctx context.Context, tx pgx.Tx, input models.CreateOrderInput, buyerID int) (int, error) {
 	var totalPrice float64
 	var orderItems []models.OrderItem
 
-	for _, item := range input.Items {
-		var price float64
-		var quantityInStock int
-		err := tx.QueryRow(ctx, "SELECT price, quantity FROM products WHERE id = $1 FOR UPDATE", item.ProductID).Scan(&price, &quantityInStock)
+	for _, itemInput := range input.Items {
+		item, err := reserveStock(ctx, tx, itemInput)
 		if err != nil {
-			return nil, fmt.Errorf("product not found: %w", err)
+			return 0, err
 		}
-		if quantityInStock < item.Quantity {
-			return nil, fmt.Errorf("not enough stock for product ID %d", item.ProductID)
+		totalPrice += item.Price * item.Quantity
+		orderItems = append(orderItems, item)
+	}
+	if input.TotalPrice != nil {
+		totalPrice = *input.TotalPrice
+	}
+	fulfilment, err := resolveFulfilment(ctx, tx, input, totalPrice)
+	if err != nil {
+		return 0, err
+	}
+	totalPrice += fulfilment.Fee
+
+	return insertOrder(ctx, tx, buyerID, input.ProducerID, totalPrice, orderItems, fulfilment)
+}
+
+// countPreorders returns how many of the items are pre-orders, refusing a mix of
+// pre-order items and items in stock.
+func countPreorders(items []models.OrderItem) (int, error) {
+	preorders := 0
+	for _, item := range items {
+		if item.Preorder {
+			preorders++
 		}
-		totalPrice += price * float64(item.Quantity)
-		orderItems = append(orderItems, models.OrderItem{ProductID: item.ProductID, Quantity: item.Quantity, Price: price})
 	}
+	if preorders > 0 && preorders < len(items) {
+		return 0, itemErrorf("pre-order items must be ordered separately from items in stock")
+	}
+	return preorders, nil
+}
 
-	var orderID int
-	orderQuery := `INSERT INTO orders (buyer_id, producer_id, total_price) VALUES ($1, $2, $3) RETURNING id`
-	err = tx.QueryRow(ctx, orderQuery, buyerID, input.ProducerID, totalPrice).Scan(&orderID)
+// insertOrder writes an order and its items. Stock must already have been taken.
+// Orders of pre-order items start out preordered; an order cannot mix them with
+// items in stock.
+func insertOrder(ctx context.Context, tx pgx.Tx, buyerID, producerID int, totalPrice float64, items []models.OrderItem, fulfilment *models.Fulfilment) (int, error) {
+	if len(items) == 0 {
+		return 0, itemErrorf("an order needs at least one item")
+	}
+	preorders, err := countPreorders(items)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 
-	for _, item := range orderItems {
-		itemQuery := `INSERT INTO order_items (order_id, product_id, quantity, price) VALUES ($1, $2, $3, $4)`
-		_, err = tx.Exec(ctx, itemQuery, orderID, item.ProductID, item.Quantity, item.Price)
-		if err != nil {
-			return nil, err
-		}
-		updateProductQuery := `UPDATE products SET quantity = quantity - $1 WHERE id = $2`
-		_, err = tx.Exec(ctx, updateProductQuery, item.Quantity, item.ProductID)
+	status := models.OrderPending
+	if preorders > 0 {
+		status = models.OrderPreordered
+	}
+	var orderID int
+	// The origin for food miles is the producer's storefront, or the first product's
+	// location when they have none.
+	orderQuery := `INSERT INTO orders (buyer_id, producer_id, total_price, pickup_point_id, delivery_option_id, fulfilment, slot_id, status, origin)
+                   VALUES ($1, $2, $3, $4, $5, $6, $7, $8, COALESCE((SELECT location FROM producer_profiles WHERE user_id = $2),
+                                                                    (SELECT location FROM products WHERE id = $9)))
+                   RETURNING id`
+	err = tx.QueryRow(ctx, orderQuery, buyerID, producerID, totalPrice, fulfilment.PickupPointID, fulfilment.DeliveryOptionID, fulfilment, fulfilment.SlotID,
+		status, items[0].ProductID).Scan(&orderID)
+	if err != nil {
+		return 0, err
+	}
+	for _, item := range items {
+		itemQuery := `INSERT INTO order_items (order_id, product_id, variant_id, product_name, variant_name, unit, quantity, price, batches, preorder)
+                      VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8, $9, $10)`
+		_, err := tx.Exec(ctx, itemQuery, orderID, item.ProductID, item.VariantID, item.ProductName, item.VariantName, item.Unit, item.Quantity, item.Price, batchesParam(item.Batches), item.Preorder)
 		if err != nil {
-			return nil, err
+			return 0, err
 		}
 	}
-
-	if err := tx.Commit(ctx); err != nil {
-		return nil, err
-	}
-	return s.GetOrderByID(ctx, orderID)
+	return orderID, nil
 }
 
 func (s *Store) GetOrderByID(ctx context.Context, orderID int) (*models.Order, error) {
 	var order models.Order
-	orderQuery := `SELECT id, buyer_id, producer_id, total_price, status, created_at FROM orders WHERE id = $1`
-	err := s.db.QueryRow(ctx, orderQuery, orderID).Scan(&order.ID, &order.BuyerID, &order.ProducerID, &order.TotalPrice, &order.Status, &order.CreatedAt)
+	orderQuery := `SELECT id, buyer_id, producer_id, total_price, status, created_at, fulfilment FROM orders WHERE id = $1`
+	err := s.db.QueryRow(ctx, orderQuery, orderID).Scan(&order.ID, &order.BuyerID, &order.ProducerID, &order.TotalPrice, &order.Status, &order.CreatedAt, &order.Fulfilment)
 	if err != nil {
 		return nil, err
 	}
 
-	itemsQuery := `SELECT id, order_id, product_id, quantity, price FROM order_items WHERE order_id = $1`
+	itemsQuery := `SELECT id, order_id, product_id, variant_id, product_name, COALESCE(variant_name, ''), unit, quantity, price, batches, preorder FROM order_items WHERE order_id = $1`
 	rows, err := s.db.Query(ctx, itemsQuery, orderID)
 	if err != nil {
 		return nil, err
@@ -177,7 +357,7 @@ func (s *Store) GetOrderByID(ctx context.Context, orderID int) (*models.Order, e
 	var items []models.OrderItem
 	for rows.Next() {
 		var item models.OrderItem
-		if err := rows.Scan(&item.ID, &item.OrderID, &item.ProductID, &item.Quantity, &item.Price); err != nil {
+		if err := rows.Scan(&item.ID, &item.OrderID, &item.ProductID, &item.VariantID, &item.ProductName, &item.VariantName, &item.Unit, &item.Quantity, &item.Price, &item.Batches, &item.Preorder); err != nil {
 			return nil, err
 		}
 		items = append(items, item)
@@ -210,12 +390,42 @@ func (s *Store) GetOrdersForUser(ctx context.Context, userID int) ([]models.Orde
 	return orders, nil
 }
 
+// UpdateOrderStatus sets an order's status. Cancelling an order gives its time slot
+// place back, and is final.
 func (s *Store) UpdateOrderStatus(ctx context.Context, orderID int, status string) (*models.Order, error) {
-	query := `UPDATE orders SET status = $1 WHERE id = $2`
-	_, err := s.db.Exec(ctx, query, status, orderID)
+	tx, err := s.db.Begin(ctx)
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback(ctx)
+
+	var oldStatus string
+	var slotID *int
+	if err := tx.QueryRow(ctx, `SELECT status, slot_id FROM orders WHERE id = $1 FOR UPDATE`, orderID).Scan(&oldStatus, &slotID); err != nil {
+		return nil, err
+	}
+	if oldStatus == models.OrderCancelled && status != models.OrderCancelled {
+		return nil, ErrOrderCancelled
+	}
+	if (oldStatus == models.OrderPreordered || status == models.OrderPreordered) && status != models.OrderCancelled {
+		return nil, ErrPreorderWaiting
+	}
+	if _, err := tx.Exec(ctx, `UPDATE orders SET status = $1 WHERE id = $2`, status, orderID); err != nil {
+		return nil, err
+	}
+	if oldStatus == models.OrderPreordered {
+		if err := cancelPreorder(ctx, tx, orderID); err != nil {
+			return nil, err
+		}
+	}
+	if status == models.OrderCancelled && oldStatus != models.OrderCancelled && slotID != nil {
+		if err := releaseSlot(ctx, tx, *slotID); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
 	return s.GetOrderByID(ctx, orderID)
 }
 
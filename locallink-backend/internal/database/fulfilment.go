@@ -0,0 +1,257 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/LocalLink/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrInvalidZone is returned for a delivery zone polygon PostGIS considers invalid,
+// such as one that crosses itself.
+var ErrInvalidZone = errors.New("delivery zone is not a valid polygon")
+
+// FulfilmentError is returned for a pickup, delivery or time slot choice the buyer
+// has to change, such as an address outside the delivery area.
+type FulfilmentError struct {
+	msg string
+}
+
+func (e *FulfilmentError) Error() string { return e.msg }
+
+func fulfilmentErrorf(format string, args ...any) error {
+	return &FulfilmentError{msg: fmt.Sprintf(format, args...)}
+}
+
+// Pickup Point and Delivery Option Methods
+func (s *Store) CreatePickupPoint(ctx context.Context, point *models.PickupPoint) error {
+	if point.OpeningWindows == nil {
+		point.OpeningWindows = []models.OpeningHours{}
+	}
+	query := `INSERT INTO pickup_points (producer_id, name, address, location, opening_windows)
+              VALUES ($1, $2, $3, ST_MakePoint($4, $5)::geography, $6) RETURNING id, active, created_at`
+	return s.db.QueryRow(ctx, query, point.ProducerID, point.Name, point.Address, point.Longitude, point.Latitude, point.OpeningWindows).
+		Scan(&point.ID, &point.Active, &point.CreatedAt)
+}
+
+const pickupPointColumns = `id, producer_id, name, address, ST_Y(location::geometry), ST_X(location::geometry), opening_windows, active, created_at`
+
+func scanPickupPoint(row pgx.Row, p *models.PickupPoint) error {
+	return row.Scan(&p.ID, &p.ProducerID, &p.Name, &p.Address, &p.Latitude, &p.Longitude, &p.OpeningWindows, &p.Active, &p.CreatedAt)
+}
+
+func (s *Store) GetPickupPoint(ctx context.Context, pointID int) (*models.PickupPoint, error) {
+	var p models.PickupPoint
+	query := `SELECT ` + pickupPointColumns + ` FROM pickup_points WHERE id = $1`
+	err := scanPickupPoint(s.db.QueryRow(ctx, query, pointID), &p)
+	return &p, err
+}
+
+func (s *Store) GetPickupPointsByProducer(ctx context.Context, producerID int) ([]models.PickupPoint, error) {
+	query := `SELECT ` + pickupPointColumns + ` FROM pickup_points WHERE producer_id = $1 AND active ORDER BY name, id`
+	rows, err := s.db.Query(ctx, query, producerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	points := []models.PickupPoint{}
+	for rows.Next() {
+		var p models.PickupPoint
+		if err := scanPickupPoint(rows, &p); err != nil {
+			return nil, err
+		}
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
+
+// DeactivatePickupPoint stops the point being offered. Past orders keep referring to it.
+func (s *Store) DeactivatePickupPoint(ctx context.Context, pointID int) error {
+	_, err := s.db.Exec(ctx, `UPDATE pickup_points SET active = FALSE WHERE id = $1`, pointID)
+	return err
+}
+
+func (s *Store) CreateDeliveryOption(ctx context.Context, option *models.DeliveryOption) error {
+	query := `INSERT INTO delivery_options (producer_id, name, radius_meters, fee, minimum_order)
+              VALUES ($1, $2, $3, $4, $5) RETURNING id, active, created_at`
+	return s.db.QueryRow(ctx, query, option.ProducerID, option.Name, option.RadiusMeters, option.Fee, option.MinimumOrder).
+		Scan(&option.ID, &option.Active, &option.CreatedAt)
+}
+
+const deliveryOptionColumns = `id, producer_id, name, radius_meters, fee, minimum_order, active, created_at`
+
+func scanDeliveryOption(row pgx.Row, o *models.DeliveryOption) error {
+	return row.Scan(&o.ID, &o.ProducerID, &o.Name, &o.RadiusMeters, &o.Fee, &o.MinimumOrder, &o.Active, &o.CreatedAt)
+}
+
+func (s *Store) GetDeliveryOption(ctx context.Context, optionID int) (*models.DeliveryOption, error) {
+	var o models.DeliveryOption
+	query := `SELECT ` + deliveryOptionColumns + ` FROM delivery_options WHERE id = $1`
+	err := scanDeliveryOption(s.db.QueryRow(ctx, query, optionID), &o)
+	return &o, err
+}
+
+func (s *Store) GetDeliveryOptionsByProducer(ctx context.Context, producerID int) ([]models.DeliveryOption, error) {
+	query := `SELECT ` + deliveryOptionColumns + ` FROM delivery_options WHERE producer_id = $1 AND active ORDER BY fee, id`
+	rows, err := s.db.Query(ctx, query, producerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	options := []models.DeliveryOption{}
+	for rows.Next() {
+		var o models.DeliveryOption
+		if err := scanDeliveryOption(rows, &o); err != nil {
+			return nil, err
+		}
+		options = append(options, o)
+	}
+	return options, rows.Err()
+}
+
+func (s *Store) DeactivateDeliveryOption(ctx context.Context, optionID int) error {
+	_, err := s.db.Exec(ctx, `UPDATE delivery_options SET active = FALSE WHERE id = $1`, optionID)
+	return err
+}
+
+// ReplaceDeliveryZones swaps a delivery option's zones for a new set in one
+// transaction. Polygons are stored as multipolygons; PostGIS rejects invalid ones
+// with ErrInvalidZone.
+func (s *Store) ReplaceDeliveryZones(ctx context.Context, optionID int, zones []models.DeliveryZone) ([]models.DeliveryZone, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM delivery_zones WHERE delivery_option_id = $1`, optionID); err != nil {
+		return nil, err
+	}
+	query := `INSERT INTO delivery_zones (delivery_option_id, name, fee, area)
+              SELECT $1, $2, $3, ST_Multi(g)::geography FROM ST_SetSRID(ST_GeomFromGeoJSON($4), 4326) AS g WHERE ST_IsValid(g)
+              RETURNING id`
+	for i := range zones {
+		zones[i].DeliveryOptionID = optionID
+		err := tx.QueryRow(ctx, query, optionID, zones[i].Name, zones[i].Fee, string(zones[i].Area)).Scan(&zones[i].ID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%w: zone %d", ErrInvalidZone, i+1)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return zones, nil
+}
+
+func (s *Store) GetDeliveryZones(ctx context.Context, optionID int) ([]models.DeliveryZone, error) {
+	query := `SELECT id, delivery_option_id, name, fee, ST_AsGeoJSON(area)::json FROM delivery_zones WHERE delivery_option_id = $1 ORDER BY fee, id`
+	rows, err := s.db.Query(ctx, query, optionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	zones := []models.DeliveryZone{}
+	for rows.Next() {
+		var z models.DeliveryZone
+		if err := rows.Scan(&z.ID, &z.DeliveryOptionID, &z.Name, &z.Fee, &z.Area); err != nil {
+			return nil, err
+		}
+		zones = append(zones, z)
+	}
+	return zones, rows.Err()
+}
+
+// GetOrderDistance returns how far an order travels from where it set off, snapshotted
+// when it was placed, to its pickup point or delivery address. For a pickup that is
+// the producer's leg to the pickup point; the buyer's own trip there isn't known. It
+// returns nil when either end is unknown.
+func (s *Store) GetOrderDistance(ctx context.Context, orderID int) (*float64, error) {
+	var distance *float64
+	query := `SELECT ST_Distance(o.origin, ST_MakePoint((o.fulfilment->>'longitude')::float8, (o.fulfilment->>'latitude')::float8)::geography)
+              FROM orders o WHERE o.id = $1 AND o.fulfilment->>'latitude' IS NOT NULL`
+	err := s.db.QueryRow(ctx, query, orderID).Scan(&distance)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return distance, err
+}
+
+// resolveFulfilment checks the buyer's choice of pickup point or delivery option
+// against the producer's offer and returns the snapshot to store on the order.
+// Delivery addresses must be inside one of the option's zones, which sets the fee, or
+// within its radius of the producer's storefront when it has no zones, and the item
+// subtotal must meet the option's minimum. A time slot is booked when the choice uses
+// slots.
+func resolveFulfilment(ctx context.Context, tx pgx.Tx, input models.CreateOrderInput, subtotal float64) (*models.Fulfilment, error) {
+	switch {
+	case input.PickupPointID != nil && input.DeliveryOptionID != nil:
+		return nil, fulfilmentErrorf("choose either a pickup point or a delivery option, not both")
+	case input.PickupPointID != nil:
+		f := &models.Fulfilment{Type: models.FulfilmentPickup, PickupPointID: input.PickupPointID}
+		var lat, lon float64
+		query := `SELECT name, address, ST_Y(location::geometry), ST_X(location::geometry) FROM pickup_points
+                  WHERE id = $1 AND producer_id = $2 AND active`
+		if err := tx.QueryRow(ctx, query, *input.PickupPointID, input.ProducerID).Scan(&f.Name, &f.Address, &lat, &lon); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, fulfilmentErrorf("pickup point %d is not offered by this producer", *input.PickupPointID)
+			}
+			return nil, err
+		}
+		f.Latitude, f.Longitude = &lat, &lon
+		return f, bookSlot(ctx, tx, input.SlotID, f)
+	case input.DeliveryOptionID != nil:
+		addr := input.DeliveryAddress
+		if addr == nil || addr.Address == "" {
+			return nil, fulfilmentErrorf("a delivery address is required for delivery")
+		}
+		if addr.Latitude < -90 || addr.Latitude > 90 || addr.Longitude < -180 || addr.Longitude > 180 {
+			return nil, fulfilmentErrorf("delivery address latitude or longitude is out of range")
+		}
+		f := &models.Fulfilment{Type: models.FulfilmentDelivery, DeliveryOptionID: input.DeliveryOptionID, Address: addr.Address}
+		f.Latitude, f.Longitude = &addr.Latitude, &addr.Longitude
+		var minimum float64
+		var inRange, hasZones bool
+		query := `SELECT d.name, d.fee, d.minimum_order,
+                  COALESCE(d.radius_meters IS NOT NULL AND ST_DWithin(pp.location, ST_MakePoint($3, $4)::geography, d.radius_meters), FALSE),
+                  EXISTS (SELECT 1 FROM delivery_zones z WHERE z.delivery_option_id = d.id)
+                  FROM delivery_options d LEFT JOIN producer_profiles pp ON pp.user_id = d.producer_id
+                  WHERE d.id = $1 AND d.producer_id = $2 AND d.active`
+		err := tx.QueryRow(ctx, query, *input.DeliveryOptionID, input.ProducerID, addr.Longitude, addr.Latitude).Scan(&f.Name, &f.Fee, &minimum, &inRange, &hasZones)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, fulfilmentErrorf("delivery option %d is not offered by this producer", *input.DeliveryOptionID)
+			}
+			return nil, err
+		}
+		if hasZones {
+			// Zones replace the radius; where they overlap the buyer gets the cheapest.
+			// A zone without a fee of its own charges the option's.
+			query = `SELECT z.name, COALESCE(z.fee, d.fee) FROM delivery_zones z JOIN delivery_options d ON d.id = z.delivery_option_id
+                     WHERE z.delivery_option_id = $1 AND ST_Covers(z.area, ST_MakePoint($2, $3)::geography)
+                     ORDER BY 2 LIMIT 1`
+			err := tx.QueryRow(ctx, query, *input.DeliveryOptionID, addr.Longitude, addr.Latitude).Scan(&f.DeliveryZone, &f.Fee)
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, fulfilmentErrorf("the delivery address is outside this delivery option's zones")
+			}
+			if err != nil {
+				return nil, err
+			}
+		} else if !inRange {
+			return nil, fulfilmentErrorf("the delivery address is outside this delivery option's range")
+		}
+		if subtotal < minimum {
+			return nil, fulfilmentErrorf("delivery needs a minimum order of %.2f", minimum)
+		}
+		return f, bookSlot(ctx, tx, input.SlotID, f)
+	}
+	return nil, fulfilmentErrorf("choose a pickup point or a delivery option")
+}
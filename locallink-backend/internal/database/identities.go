@@ -0,0 +1,23 @@
+package database
+
+import (
+	"context"
+
+	"github.com/LocalLink/internal/models"
+)
+
+// Identity Methods
+func (s *Store) GetUserByIdentity(ctx context.Context, provider, subject string) (*models.User, error) {
+	var userID int
+	query := `SELECT user_id FROM user_identities WHERE provider = $1 AND subject = $2`
+	if err := s.db.QueryRow(ctx, query, provider, subject).Scan(&userID); err != nil {
+		return nil, err
+	}
+	return s.GetUserByID(ctx, userID)
+}
+
+func (s *Store) LinkIdentity(ctx context.Context, userID int, provider, subject, email string) error {
+	query := `INSERT INTO user_identities (user_id, provider, subject, email) VALUES ($1, $2, $3, $4)`
+	_, err := s.db.Exec(ctx, query, userID, provider, subject, email)
+	return err
+}
@@ -0,0 +1,89 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/LocalLink/internal/images"
+	"github.com/LocalLink/internal/models"
+)
+
+// Product Image Methods
+func (s *Store) CreateProductImage(ctx context.Context, img *models.ProductImage) error {
+	query := `INSERT INTO product_images (product_id, storage_prefix, original_ext, variant_ext, width, height, position)
+              VALUES ($1, $2, $3, $4, $5, $6, (SELECT COALESCE(MAX(position) + 1, 0) FROM product_images WHERE product_id = $1))
+              RETURNING id, position, created_at`
+	err := s.db.QueryRow(ctx, query, img.ProductID, img.StoragePrefix, img.OriginalExt, img.VariantExt, img.Width, img.Height).Scan(&img.ID, &img.Position, &img.CreatedAt)
+	if err != nil {
+		return err
+	}
+	img.URLs = imageURLs(img.ID)
+	return nil
+}
+
+func (s *Store) CountProductImages(ctx context.Context, productID int) (int, error) {
+	var count int
+	err := s.db.QueryRow(ctx, `SELECT COUNT(*) FROM product_images WHERE product_id = $1`, productID).Scan(&count)
+	return count, err
+}
+
+func (s *Store) GetProductImage(ctx context.Context, imageID int) (*models.ProductImage, error) {
+	var img models.ProductImage
+	query := `SELECT id, product_id, storage_prefix, original_ext, variant_ext, width, height, position, created_at FROM product_images WHERE id = $1`
+	err := s.db.QueryRow(ctx, query, imageID).Scan(&img.ID, &img.ProductID, &img.StoragePrefix, &img.OriginalExt, &img.VariantExt, &img.Width, &img.Height, &img.Position, &img.CreatedAt)
+	img.URLs = imageURLs(img.ID)
+	return &img, err
+}
+
+func (s *Store) GetImagesForProducts(ctx context.Context, productIDs []int) (map[int][]models.ProductImage, error) {
+	query := `SELECT id, product_id, storage_prefix, original_ext, variant_ext, width, height, position, created_at
+              FROM product_images WHERE product_id = ANY($1) ORDER BY product_id, position`
+	rows, err := s.db.Query(ctx, query, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	imagesByProduct := make(map[int][]models.ProductImage)
+	for rows.Next() {
+		var img models.ProductImage
+		if err := rows.Scan(&img.ID, &img.ProductID, &img.StoragePrefix, &img.OriginalExt, &img.VariantExt, &img.Width, &img.Height, &img.Position, &img.CreatedAt); err != nil {
+			return nil, err
+		}
+		img.URLs = imageURLs(img.ID)
+		imagesByProduct[img.ProductID] = append(imagesByProduct[img.ProductID], img)
+	}
+	return imagesByProduct, rows.Err()
+}
+
+func (s *Store) DeleteProductImage(ctx context.Context, imageID int) error {
+	_, err := s.db.Exec(ctx, `DELETE FROM product_images WHERE id = $1`, imageID)
+	return err
+}
+
+// attachImages loads the images of each product in one query.
+func (s *Store) attachImages(ctx context.Context, products []models.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+	ids := make([]int, len(products))
+	for i, p := range products {
+		ids[i] = p.ID
+	}
+	imagesByProduct, err := s.GetImagesForProducts(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for i := range products {
+		products[i].Images = imagesByProduct[products[i].ID]
+	}
+	return nil
+}
+
+func imageURLs(imageID int) map[string]string {
+	urls := map[string]string{"original": fmt.Sprintf("/images/%d/original", imageID)}
+	for _, size := range images.Sizes {
+		urls[size.Name] = fmt.Sprintf("/images/%d/%s", imageID, size.Name)
+	}
+	return urls
+}
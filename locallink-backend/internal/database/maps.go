@@ -0,0 +1,96 @@
+package database
+
+import (
+	"context"
+
+	"github.com/LocalLink/internal/models"
+)
+
+// Product Map Methods
+
+// inBox matches products inside the viewport in $1-$4. The box is compared in plain
+// longitude/latitude, as the map draws it; as geography its edges would bend along
+// great circles and a box wider than 180° would turn inside out.
+const inBox = `p.location::geometry && ST_MakeEnvelope($1, $2, $3, $4, 4326)`
+
+// GetProductsInBox lists up to limit available products inside a map viewport.
+func (s *Store) GetProductsInBox(ctx context.Context, box models.BoundingBox, limit int) ([]models.Product, error) {
+	query := `SELECT ` + productColumns + ` FROM products p
+              WHERE ` + inBox + ` AND ` + availableProduct + `
+              ORDER BY id LIMIT $5`
+	return s.queryMapProducts(ctx, query, box.MinLon, box.MinLat, box.MaxLon, box.MaxLat, limit)
+}
+
+// GetProductClusters snaps the available products inside a viewport to a grid of
+// cellDegrees and returns one cluster per occupied cell. A cell holding a single
+// product carries that product so it can be shown on its own.
+func (s *Store) GetProductClusters(ctx context.Context, box models.BoundingBox, cellDegrees float64) ([]models.ProductCluster, error) {
+	query := `SELECT ST_Y(ST_Centroid(ST_Collect(location::geometry))), ST_X(ST_Centroid(ST_Collect(location::geometry))), COUNT(*), MIN(price),
+              CASE WHEN COUNT(*) = 1 THEN MIN(id) END
+              FROM products p
+              WHERE ` + inBox + ` AND ` + availableProduct + `
+              GROUP BY ST_SnapToGrid(location::geometry, $5)`
+	rows, err := s.db.Query(ctx, query, box.MinLon, box.MinLat, box.MaxLon, box.MaxLat, cellDegrees)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var clusters []models.ProductCluster
+	singles := make(map[int]int) // product ID to its cluster's index
+	for rows.Next() {
+		var c models.ProductCluster
+		var productID *int
+		if err := rows.Scan(&c.Latitude, &c.Longitude, &c.Count, &c.MinPrice, &productID); err != nil {
+			return nil, err
+		}
+		if productID != nil {
+			singles[*productID] = len(clusters)
+		}
+		clusters = append(clusters, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(singles) == 0 {
+		return clusters, nil
+	}
+
+	ids := make([]int, 0, len(singles))
+	for id := range singles {
+		ids = append(ids, id)
+	}
+	products, err := s.queryMapProducts(ctx, `SELECT `+productColumns+` FROM products p WHERE id = ANY($1)`, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range products {
+		clusters[singles[products[i].ID]].Product = &products[i]
+	}
+	return clusters, nil
+}
+
+// queryMapProducts runs a product query and attaches the batches used to price them.
+func (s *Store) queryMapProducts(ctx context.Context, query string, args ...interface{}) ([]models.Product, error) {
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var products []models.Product
+	for rows.Next() {
+		var p models.Product
+		if err := scanProduct(rows, &p); err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := s.attachBatches(ctx, products); err != nil {
+		return nil, err
+	}
+	return products, nil
+}
@@ -0,0 +1,130 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// MFA Methods
+func (s *Store) SetTOTPSecret(ctx context.Context, userID int, secret string) error {
+	_, err := s.db.Exec(ctx, `UPDATE users SET totp_secret = $1, totp_last_step = NULL WHERE id = $2 AND mfa_enabled = FALSE`, secret, userID)
+	return err
+}
+
+func (s *Store) EnableMFA(ctx context.Context, userID int, backupCodeHashes []string) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `UPDATE users SET mfa_enabled = TRUE WHERE id = $1`, userID); err != nil {
+		return err
+	}
+	if err := replaceBackupCodes(ctx, tx, userID, backupCodeHashes); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (s *Store) RegenerateBackupCodes(ctx context.Context, userID int, backupCodeHashes []string) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := replaceBackupCodes(ctx, tx, userID, backupCodeHashes); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (s *Store) DisableMFA(ctx context.Context, userID int) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `UPDATE users SET mfa_enabled = FALSE, totp_secret = NULL, totp_last_step = NULL WHERE id = $1`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM mfa_backup_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ConsumeBackupCode marks a matching unused backup code as used and reports whether one was found.
+func (s *Store) ConsumeBackupCode(ctx context.Context, userID int, codeHash string) (bool, error) {
+	query := `UPDATE mfa_backup_codes SET used_at = NOW() WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`
+	tag, err := s.db.Exec(ctx, query, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// UseTOTPStep records the time step of an accepted TOTP code and reports false if
+// that step, or a later one, was already used.
+func (s *Store) UseTOTPStep(ctx context.Context, userID int, step int64) (bool, error) {
+	query := `UPDATE users SET totp_last_step = $1 WHERE id = $2 AND (totp_last_step IS NULL OR totp_last_step < $1)`
+	tag, err := s.db.Exec(ctx, query, step, userID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// MFALocked reports whether the account's MFA logins are locked after too many
+// failed codes.
+func (s *Store) MFALocked(ctx context.Context, userID int) (bool, error) {
+	var locked bool
+	err := s.db.QueryRow(ctx, `SELECT COALESCE(mfa_locked_until > NOW(), FALSE) FROM users WHERE id = $1`, userID).Scan(&locked)
+	return locked, err
+}
+
+// RecordMFAFailure counts a failed MFA login code. The maxAttempts-th failure in a row
+// locks MFA logins for the lockout period and starts the count again.
+func (s *Store) RecordMFAFailure(ctx context.Context, userID, maxAttempts int, lockout time.Duration) error {
+	query := `UPDATE users SET
+              mfa_locked_until = CASE WHEN mfa_failed_attempts + 1 >= $2 THEN NOW() + $3 * INTERVAL '1 second' ELSE mfa_locked_until END,
+              mfa_failed_attempts = CASE WHEN mfa_failed_attempts + 1 >= $2 THEN 0 ELSE mfa_failed_attempts + 1 END
+              WHERE id = $1`
+	_, err := s.db.Exec(ctx, query, userID, maxAttempts, lockout.Seconds())
+	return err
+}
+
+func (s *Store) ResetMFAFailures(ctx context.Context, userID int) error {
+	_, err := s.db.Exec(ctx, `UPDATE users SET mfa_failed_attempts = 0 WHERE id = $1 AND mfa_failed_attempts > 0`, userID)
+	return err
+}
+
+// SpendMFAChallenge records an MFA challenge token as used and reports false if it
+// already was. Records of expired challenges are cleared as it goes.
+func (s *Store) SpendMFAChallenge(ctx context.Context, challengeID string, expiresAt time.Time) (bool, error) {
+	if _, err := s.db.Exec(ctx, `DELETE FROM mfa_challenges_used WHERE expires_at < NOW()`); err != nil {
+		return false, err
+	}
+	query := `INSERT INTO mfa_challenges_used (id, expires_at) VALUES ($1, $2) ON CONFLICT (id) DO NOTHING`
+	tag, err := s.db.Exec(ctx, query, challengeID, expiresAt)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func replaceBackupCodes(ctx context.Context, tx pgx.Tx, userID int, hashes []string) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM mfa_backup_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	for _, hash := range hashes {
+		if _, err := tx.Exec(ctx, `INSERT INTO mfa_backup_codes (user_id, code_hash) VALUES ($1, $2)`, userID, hash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/LocalLink/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrPreorderWaiting is returned for a status change on a pre-order still waiting for
+// its harvest. Until the release job converts it, it can only be cancelled.
+var ErrPreorderWaiting = errors.New("pre-orders can only be cancelled until their harvest is released")
+
+// Pre-order Methods
+
+// EnablePreorder opens a product for pre-orders ahead of its harvest.
+func (s *Store) EnablePreorder(ctx context.Context, productID int, availableFrom time.Time, limit float64) (*models.Product, error) {
+	query := `UPDATE products SET available_from = $1, preorder_limit = $2, released_at = NULL WHERE id = $3`
+	if _, err := s.db.Exec(ctx, query, availableFrom, limit, productID); err != nil {
+		return nil, err
+	}
+	return s.GetProductByID(ctx, productID)
+}
+
+// ReleaseHarvest marks a pre-order product's harvest as available and adds it to
+// stock. Waiting pre-orders are then converted by ReleasePreorders.
+func (s *Store) ReleaseHarvest(ctx context.Context, productID int, quantity float64) (*models.Product, error) {
+	query := `UPDATE products SET quantity = quantity + $1, released_at = NOW() WHERE id = $2 AND released_at IS NULL`
+	if _, err := s.db.Exec(ctx, query, quantity, productID); err != nil {
+		return nil, err
+	}
+	return s.GetProductByID(ctx, productID)
+}
+
+// ReleasePreorders converts pre-orders whose harvests have all been released into
+// regular orders, oldest first, taking their stock. Pre-orders the harvest cannot
+// cover are cancelled.
+func (s *Store) ReleasePreorders(ctx context.Context) ([]models.PreorderRelease, error) {
+	var released []models.PreorderRelease
+	for {
+		tx, err := s.db.Begin(ctx)
+		if err != nil {
+			return released, err
+		}
+		release, err := releaseNextPreorder(ctx, tx)
+		if err == nil {
+			err = tx.Commit(ctx)
+		}
+		if err != nil {
+			tx.Rollback(ctx)
+			if errors.Is(err, pgx.ErrNoRows) {
+				return released, nil
+			}
+			return released, err
+		}
+		released = append(released, release)
+	}
+}
+
+func releaseNextPreorder(ctx context.Context, tx pgx.Tx) (models.PreorderRelease, error) {
+	release := models.PreorderRelease{Status: models.OrderPending}
+	var slotID *int
+	query := `SELECT id, buyer_id, slot_id FROM orders o WHERE status = 'preordered'
+              AND NOT EXISTS (SELECT 1 FROM order_items oi JOIN products p ON p.id = oi.product_id
+                              WHERE oi.order_id = o.id AND oi.preorder AND p.released_at IS NULL)
+              ORDER BY created_at, id LIMIT 1 FOR UPDATE SKIP LOCKED`
+	if err := tx.QueryRow(ctx, query).Scan(&release.OrderID, &release.BuyerID, &slotID); err != nil {
+		return release, err
+	}
+
+	rows, err := tx.Query(ctx, `SELECT product_id, quantity FROM order_items WHERE order_id = $1 AND preorder ORDER BY product_id`, release.OrderID)
+	if err != nil {
+		return release, err
+	}
+	var items []models.OrderItem
+	for rows.Next() {
+		var item models.OrderItem
+		if err := rows.Scan(&item.ProductID, &item.Quantity); err != nil {
+			rows.Close()
+			return release, err
+		}
+		items = append(items, item)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return release, err
+	}
+
+	for _, item := range items {
+		var stock float64
+		if err := tx.QueryRow(ctx, `SELECT quantity FROM products WHERE id = $1 FOR UPDATE`, item.ProductID).Scan(&stock); err != nil {
+			return release, err
+		}
+		if stock < item.Quantity {
+			release.Status = models.OrderCancelled
+		}
+	}
+	for _, item := range items {
+		query := `UPDATE products SET preordered_quantity = GREATEST(preordered_quantity - $1, 0) WHERE id = $2`
+		if release.Status == models.OrderPending {
+			query = `UPDATE products SET preordered_quantity = GREATEST(preordered_quantity - $1, 0), quantity = quantity - $1 WHERE id = $2`
+		}
+		if _, err := tx.Exec(ctx, query, item.Quantity, item.ProductID); err != nil {
+			return release, err
+		}
+	}
+	if release.Status == models.OrderCancelled && slotID != nil {
+		if err := releaseSlot(ctx, tx, *slotID); err != nil {
+			return release, err
+		}
+	}
+	_, err = tx.Exec(ctx, `UPDATE orders SET status = $1 WHERE id = $2`, release.Status, release.OrderID)
+	return release, err
+}
+
+// cancelPreorder gives a cancelled pre-order's quantities back to the products' pre-order
+// limits.
+func cancelPreorder(ctx context.Context, tx pgx.Tx, orderID int) error {
+	query := `UPDATE products p SET preordered_quantity = GREATEST(p.preordered_quantity - t.quantity, 0)
+              FROM (SELECT product_id, SUM(quantity) AS quantity FROM order_items WHERE order_id = $1 AND preorder GROUP BY product_id) t
+              WHERE p.id = t.product_id`
+	_, err := tx.Exec(ctx, query, orderID)
+	return err
+}
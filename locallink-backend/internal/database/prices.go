@@ -0,0 +1,35 @@
+package database
+
+import (
+	"context"
+
+	"github.com/LocalLink/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Price History Methods
+func (s *Store) GetPriceHistory(ctx context.Context, productID int) ([]models.PriceChange, error) {
+	query := `SELECT id, product_id, variant_id, price, changed_at FROM product_price_history WHERE product_id = $1 ORDER BY changed_at, id`
+	rows, err := s.db.Query(ctx, query, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []models.PriceChange
+	for rows.Next() {
+		var c models.PriceChange
+		if err := rows.Scan(&c.ID, &c.ProductID, &c.VariantID, &c.Price, &c.ChangedAt); err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
+
+func recordPrice(ctx context.Context, tx pgx.Tx, productID int, variantID *int, price float64) error {
+	query := `INSERT INTO product_price_history (product_id, variant_id, price) VALUES ($1, $2, $3)`
+	_, err := tx.Exec(ctx, query, productID, variantID, price)
+	return err
+}
@@ -0,0 +1,113 @@
+package database
+
+import (
+	"context"
+
+	"github.com/LocalLink/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Producer Profile Methods
+const producerProfileColumns = `pp.user_id, u.name, pp.farm_name, pp.bio, pp.practices, pp.certifications, pp.opening_hours, pp.pickup_address,
+                                ST_Y(pp.location::geometry), ST_X(pp.location::geometry), pp.updated_at`
+
+func scanProducerProfile(row pgx.Row, p *models.ProducerProfile, extra ...any) error {
+	dest := []any{&p.ProducerID, &p.Name, &p.FarmName, &p.Bio, &p.Practices, &p.Certifications, &p.OpeningHours, &p.PickupAddress,
+		&p.Latitude, &p.Longitude, &p.UpdatedAt}
+	return row.Scan(append(dest, extra...)...)
+}
+
+func (s *Store) UpsertProducerProfile(ctx context.Context, profile *models.ProducerProfile) (*models.ProducerProfile, error) {
+	if profile.Practices == nil {
+		profile.Practices = []string{}
+	}
+	if profile.Certifications == nil {
+		profile.Certifications = []string{}
+	}
+	if profile.OpeningHours == nil {
+		profile.OpeningHours = []models.OpeningHours{}
+	}
+	query := `INSERT INTO producer_profiles (user_id, farm_name, bio, practices, certifications, opening_hours, pickup_address, location)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, ST_MakePoint($8, $9)::geography)
+              ON CONFLICT (user_id) DO UPDATE SET farm_name = EXCLUDED.farm_name, bio = EXCLUDED.bio, practices = EXCLUDED.practices,
+              certifications = EXCLUDED.certifications, opening_hours = EXCLUDED.opening_hours, pickup_address = EXCLUDED.pickup_address,
+              location = EXCLUDED.location, updated_at = NOW()`
+	_, err := s.db.Exec(ctx, query, profile.ProducerID, profile.FarmName, profile.Bio, profile.Practices, profile.Certifications,
+		profile.OpeningHours, profile.PickupAddress, profile.Longitude, profile.Latitude)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetProducerProfile(ctx, profile.ProducerID)
+}
+
+func (s *Store) GetProducerProfile(ctx context.Context, producerID int) (*models.ProducerProfile, error) {
+	var p models.ProducerProfile
+	query := `SELECT ` + producerProfileColumns + ` FROM producer_profiles pp JOIN users u ON u.id = pp.user_id
+              WHERE pp.user_id = $1 AND u.anonymized_at IS NULL`
+	err := scanProducerProfile(s.db.QueryRow(ctx, query, producerID), &p)
+	return &p, err
+}
+
+// GetProducersNearby returns producers whose pickup location is within radius metres,
+// nearest first.
+func (s *Store) GetProducersNearby(ctx context.Context, lat, lon float64, radius int) ([]models.ProducerProfile, error) {
+	query := `SELECT ` + producerProfileColumns + `, ST_Distance(pp.location, ST_MakePoint($1, $2)::geography)
+              FROM producer_profiles pp JOIN users u ON u.id = pp.user_id
+              WHERE ST_DWithin(pp.location, ST_MakePoint($1, $2)::geography, $3) AND u.anonymized_at IS NULL
+              ORDER BY pp.location <-> ST_MakePoint($1, $2)::geography`
+	rows, err := s.db.Query(ctx, query, lon, lat, radius)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	producers := []models.ProducerProfile{}
+	for rows.Next() {
+		var p models.ProducerProfile
+		var distance float64
+		if err := scanProducerProfile(rows, &p, &distance); err != nil {
+			return nil, err
+		}
+		p.DistanceMeters = &distance
+		producers = append(producers, p)
+	}
+	return producers, rows.Err()
+}
+
+// GetPublishedProductsByProducer lists what a producer currently has on sale.
+func (s *Store) GetPublishedProductsByProducer(ctx context.Context, producerID int) ([]models.Product, error) {
+	query := `SELECT ` + productColumns + ` FROM products WHERE producer_id = $1 AND status = 'published' ORDER BY name, id`
+	rows, err := s.db.Query(ctx, query, producerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := []models.Product{}
+	for rows.Next() {
+		var p models.Product
+		if err := scanProduct(rows, &p); err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := s.attachImages(ctx, products); err != nil {
+		return nil, err
+	}
+	if err := s.attachVariants(ctx, products); err != nil {
+		return nil, err
+	}
+	return products, s.attachBatches(ctx, products)
+}
+
+// GetProducerRating summarises the reviews left on all of a producer's products.
+func (s *Store) GetProducerRating(ctx context.Context, producerID int) (models.RatingSummary, error) {
+	var summary models.RatingSummary
+	query := `SELECT COALESCE(AVG(r.rating), 0)::float8, COUNT(r.id) FROM reviews r JOIN products p ON p.id = r.product_id WHERE p.producer_id = $1`
+	err := s.db.QueryRow(ctx, query, producerID).Scan(&summary.Average, &summary.Count)
+	return summary, err
+}
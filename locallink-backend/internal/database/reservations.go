@@ -0,0 +1,235 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/LocalLink/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrReservationInactive = errors.New("reservation has expired or was already used")
+
+const reservationStatus = `CASE WHEN confirmed_at IS NOT NULL THEN 'confirmed' WHEN released_at IS NOT NULL THEN 'released'
+                           WHEN expires_at <= NOW() THEN 'expired' ELSE 'active' END`
+
+// Stock Reservation Methods
+
+// CreateReservation takes the stock for a checkout basket straight away, so the
+// quantities listed to other buyers already exclude it, and snapshots prices so the
+// buyer pays what they saw when checkout started.
+func (s *Store) CreateReservation(ctx context.Context, input models.CreateOrderInput, buyerID int, ttl time.Duration) (*models.Reservation, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if len(input.Items) == 0 {
+		return nil, itemErrorf("a reservation needs at least one item")
+	}
+	var totalPrice float64
+	var items []models.OrderItem
+	for _, itemInput := range input.Items {
+		item, err := reserveStock(ctx, tx, itemInput)
+		if err != nil {
+			return nil, err
+		}
+		totalPrice += item.Price * item.Quantity
+		items = append(items, item)
+	}
+	if _, err := countPreorders(items); err != nil {
+		return nil, err
+	}
+
+	fulfilment, err := resolveFulfilment(ctx, tx, input, totalPrice)
+	if err != nil {
+		return nil, err
+	}
+	totalPrice += fulfilment.Fee
+
+	var reservationID int
+	query := `INSERT INTO stock_reservations (buyer_id, producer_id, total_price, expires_at, fulfilment) VALUES ($1, $2, $3, $4, $5) RETURNING id`
+	if err := tx.QueryRow(ctx, query, buyerID, input.ProducerID, totalPrice, time.Now().Add(ttl), fulfilment).Scan(&reservationID); err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		itemQuery := `INSERT INTO stock_reservation_items (reservation_id, product_id, variant_id, product_name, variant_name, unit, quantity, price, batches, preorder)
+                      VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8, $9, $10)`
+		_, err := tx.Exec(ctx, itemQuery, reservationID, item.ProductID, item.VariantID, item.ProductName, item.VariantName, item.Unit, item.Quantity, item.Price, batchesParam(item.Batches), item.Preorder)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return s.GetReservation(ctx, reservationID)
+}
+
+func (s *Store) GetReservation(ctx context.Context, reservationID int) (*models.Reservation, error) {
+	var res models.Reservation
+	query := `SELECT id, buyer_id, producer_id, total_price, ` + reservationStatus + `, expires_at, created_at, order_id, fulfilment
+              FROM stock_reservations WHERE id = $1`
+	err := s.db.QueryRow(ctx, query, reservationID).Scan(&res.ID, &res.BuyerID, &res.ProducerID, &res.TotalPrice, &res.Status, &res.ExpiresAt, &res.CreatedAt, &res.OrderID, &res.Fulfilment)
+	if err != nil {
+		return nil, err
+	}
+	res.Items, err = getReservationItems(ctx, s.db, reservationID)
+	return &res, err
+}
+
+// ConfirmReservation turns an active reservation into an order. The stock was taken
+// when the reservation was made, so it is not checked again.
+func (s *Store) ConfirmReservation(ctx context.Context, reservationID int) (*models.Order, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var buyerID, producerID int
+	var totalPrice float64
+	var fulfilment *models.Fulfilment
+	query := `SELECT buyer_id, producer_id, total_price, fulfilment FROM stock_reservations
+              WHERE id = $1 AND confirmed_at IS NULL AND released_at IS NULL AND expires_at > NOW() FOR UPDATE`
+	if err := tx.QueryRow(ctx, query, reservationID).Scan(&buyerID, &producerID, &totalPrice, &fulfilment); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrReservationInactive
+		}
+		return nil, err
+	}
+	items, err := getReservationItems(ctx, tx, reservationID)
+	if err != nil {
+		return nil, err
+	}
+	if fulfilment == nil {
+		return nil, errors.New("reservation has no pickup or delivery choice; start checkout again")
+	}
+	orderID, err := insertOrder(ctx, tx, buyerID, producerID, totalPrice, items, fulfilment)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE stock_reservations SET confirmed_at = NOW(), order_id = $1 WHERE id = $2`, orderID, reservationID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return s.GetOrderByID(ctx, orderID)
+}
+
+// ReleaseReservation gives the stock of an unconfirmed reservation back. It reports
+// false when the reservation was already confirmed or released.
+func (s *Store) ReleaseReservation(ctx context.Context, reservationID int) (bool, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `SELECT id FROM stock_reservations WHERE id = $1 AND confirmed_at IS NULL AND released_at IS NULL FOR UPDATE`
+	if err := tx.QueryRow(ctx, query, reservationID).Scan(&reservationID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	if err := releaseReservation(ctx, tx, reservationID); err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+// ReleaseExpiredReservations returns the stock of every reservation that ran out
+// before it was confirmed. Reservations being confirmed at the same moment are skipped.
+func (s *Store) ReleaseExpiredReservations(ctx context.Context) (int, error) {
+	released := 0
+	for {
+		tx, err := s.db.Begin(ctx)
+		if err != nil {
+			return released, err
+		}
+		var reservationID int
+		query := `SELECT id FROM stock_reservations WHERE confirmed_at IS NULL AND released_at IS NULL AND expires_at <= NOW()
+                  ORDER BY expires_at LIMIT 1 FOR UPDATE SKIP LOCKED`
+		err = tx.QueryRow(ctx, query).Scan(&reservationID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			tx.Rollback(ctx)
+			return released, nil
+		}
+		if err == nil {
+			err = releaseReservation(ctx, tx, reservationID)
+		}
+		if err == nil {
+			err = tx.Commit(ctx)
+		}
+		if err != nil {
+			tx.Rollback(ctx)
+			return released, err
+		}
+		released++
+	}
+}
+
+func releaseReservation(ctx context.Context, tx pgx.Tx, reservationID int) error {
+	items, err := getReservationItems(ctx, tx, reservationID)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		switch {
+		case item.Preorder:
+			_, err = tx.Exec(ctx, `UPDATE products SET preordered_quantity = GREATEST(preordered_quantity - $1, 0) WHERE id = $2`, item.Quantity, item.ProductID)
+		case item.VariantID != nil:
+			_, err = tx.Exec(ctx, `UPDATE product_variants SET quantity = quantity + $1 WHERE id = $2`, item.Quantity, *item.VariantID)
+		default:
+			// Batch-tracked stock only comes back as far as its batches still exist.
+			restored := item.Quantity
+			if len(item.Batches) > 0 {
+				restored, err = restoreBatches(ctx, tx, item.Batches)
+			}
+			if err == nil {
+				_, err = tx.Exec(ctx, `UPDATE products SET quantity = quantity + $1 WHERE id = $2`, restored, item.ProductID)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	var fulfilment *models.Fulfilment
+	query := `UPDATE stock_reservations SET released_at = NOW() WHERE id = $1 RETURNING fulfilment`
+	if err := tx.QueryRow(ctx, query, reservationID).Scan(&fulfilment); err != nil {
+		return err
+	}
+	if fulfilment != nil && fulfilment.SlotID != nil {
+		return releaseSlot(ctx, tx, *fulfilment.SlotID)
+	}
+	return nil
+}
+
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+func getReservationItems(ctx context.Context, q querier, reservationID int) ([]models.OrderItem, error) {
+	query := `SELECT id, product_id, variant_id, product_name, COALESCE(variant_name, ''), unit, quantity, price, batches, preorder
+              FROM stock_reservation_items WHERE reservation_id = $1 ORDER BY id`
+	rows, err := q.Query(ctx, query, reservationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []models.OrderItem
+	for rows.Next() {
+		var item models.OrderItem
+		if err := rows.Scan(&item.ID, &item.ProductID, &item.VariantID, &item.ProductName, &item.VariantName, &item.Unit, &item.Quantity, &item.Price, &item.Batches, &item.Preorder); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
@@ -0,0 +1,151 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/LocalLink/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Saved Search Methods
+func (s *Store) CreateSavedSearch(ctx context.Context, search *models.SavedSearch) error {
+	query := `INSERT INTO saved_searches (user_id, name, query, category, location, radius_meters, max_price, email_digest)
+              VALUES ($1, $2, $3, $4, ST_MakePoint($5, $6)::geography, $7, $8, $9) RETURNING id, created_at`
+	return s.db.QueryRow(ctx, query, search.UserID, search.Name, search.Query, search.Category, search.Longitude, search.Latitude,
+		search.RadiusMeters, search.MaxPrice, search.EmailDigest).Scan(&search.ID, &search.CreatedAt)
+}
+
+const savedSearchColumns = `id, user_id, name, query, category, ST_Y(location::geometry), ST_X(location::geometry), radius_meters, max_price, email_digest, created_at`
+
+func scanSavedSearch(row pgx.Row, ss *models.SavedSearch) error {
+	return row.Scan(&ss.ID, &ss.UserID, &ss.Name, &ss.Query, &ss.Category, &ss.Latitude, &ss.Longitude, &ss.RadiusMeters, &ss.MaxPrice, &ss.EmailDigest, &ss.CreatedAt)
+}
+
+func (s *Store) GetSavedSearch(ctx context.Context, searchID int) (*models.SavedSearch, error) {
+	var ss models.SavedSearch
+	err := scanSavedSearch(s.db.QueryRow(ctx, `SELECT `+savedSearchColumns+` FROM saved_searches WHERE id = $1`, searchID), &ss)
+	return &ss, err
+}
+
+func (s *Store) GetSavedSearchesForUser(ctx context.Context, userID int) ([]models.SavedSearch, error) {
+	rows, err := s.db.Query(ctx, `SELECT `+savedSearchColumns+` FROM saved_searches WHERE user_id = $1 ORDER BY created_at, id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	searches := []models.SavedSearch{}
+	for rows.Next() {
+		var ss models.SavedSearch
+		if err := scanSavedSearch(rows, &ss); err != nil {
+			return nil, err
+		}
+		searches = append(searches, ss)
+	}
+	return searches, rows.Err()
+}
+
+func (s *Store) DeleteSavedSearch(ctx context.Context, searchID int) error {
+	_, err := s.db.Exec(ctx, `DELETE FROM saved_searches WHERE id = $1`, searchID)
+	return err
+}
+
+// MatchSavedSearches records which saved searches a just-published product matches,
+// and returns the new matches. A product matches once per search, however often it
+// is republished. Producers are not alerted about their own products.
+func (s *Store) MatchSavedSearches(ctx context.Context, productID int) ([]models.SavedSearchMatch, error) {
+	query := `WITH matched AS (
+                  INSERT INTO saved_search_matches (search_id, product_id)
+                  SELECT ss.id, p.id FROM saved_searches ss
+                  JOIN users u ON u.id = ss.user_id AND u.anonymized_at IS NULL
+                  JOIN products p ON p.id = $1
+                  WHERE ss.user_id <> p.producer_id
+                    AND ST_DWithin(ss.location, p.location, ss.radius_meters)
+                    AND (ss.category = '' OR ss.category = p.category)
+                    AND (ss.max_price IS NULL OR p.price <= ss.max_price
+                         OR EXISTS (SELECT 1 FROM product_variants v WHERE v.product_id = p.id AND v.price <= ss.max_price))
+                    AND (ss.query = '' OR to_tsvector('english', p.name || ' ' || p.description || ' ' || p.category) @@ plainto_tsquery('english', ss.query))
+                  ON CONFLICT DO NOTHING
+                  RETURNING search_id, product_id, matched_at
+              )
+              SELECT m.search_id, ss.name, ss.user_id, p.id, p.name, p.price, p.unit, m.matched_at
+              FROM matched m JOIN saved_searches ss ON ss.id = m.search_id JOIN products p ON p.id = m.product_id`
+	rows, err := s.db.Query(ctx, query, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var matches []models.SavedSearchMatch
+	for rows.Next() {
+		var m models.SavedSearchMatch
+		if err := rows.Scan(&m.SearchID, &m.SearchName, &m.UserID, &m.ProductID, &m.ProductName, &m.Price, &m.Unit, &m.MatchedAt); err != nil {
+			return nil, err
+		}
+		matches = append(matches, m)
+	}
+	return matches, rows.Err()
+}
+
+// GetPendingDigests groups, per user, the matches up to upTo that haven't been emailed
+// yet, for searches with the daily digest on that haven't had one in the last day.
+// Matches for products no longer published are left out.
+func (s *Store) GetPendingDigests(ctx context.Context, upTo time.Time) ([]models.SearchDigest, error) {
+	query := `SELECT u.id, u.email, u.name, ss.id, ss.name, p.id, p.name, p.price, p.unit, m.matched_at
+              FROM saved_search_matches m
+              JOIN saved_searches ss ON ss.id = m.search_id
+              JOIN users u ON u.id = ss.user_id
+              JOIN products p ON p.id = m.product_id
+              WHERE ss.email_digest AND m.emailed_at IS NULL AND m.matched_at <= $1 AND u.anonymized_at IS NULL
+                AND (ss.last_digest_at IS NULL OR ss.last_digest_at <= NOW() - INTERVAL '1 day')
+                AND p.status = 'published'
+              ORDER BY u.id, ss.id, m.matched_at`
+	rows, err := s.db.Query(ctx, query, upTo)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var digests []models.SearchDigest
+	for rows.Next() {
+		var userID int
+		var email, name string
+		var m models.SavedSearchMatch
+		if err := rows.Scan(&userID, &email, &name, &m.SearchID, &m.SearchName, &m.ProductID, &m.ProductName, &m.Price, &m.Unit, &m.MatchedAt); err != nil {
+			return nil, err
+		}
+		m.UserID = userID
+		if n := len(digests); n == 0 || digests[n-1].UserID != userID {
+			digests = append(digests, models.SearchDigest{UserID: userID, Email: email, Name: name})
+		}
+		d := &digests[len(digests)-1]
+		if n := len(d.SearchIDs); n == 0 || d.SearchIDs[n-1] != m.SearchID {
+			d.SearchIDs = append(d.SearchIDs, m.SearchID)
+		}
+		d.Matches = append(d.Matches, m)
+	}
+	return digests, rows.Err()
+}
+
+// MarkDigestSent records that the searches' matches up to upTo have been emailed.
+// Matches for unpublished products were left out of the digest, so they stay pending
+// until the product is published again.
+func (s *Store) MarkDigestSent(ctx context.Context, searchIDs []int, upTo time.Time) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `UPDATE saved_search_matches m SET emailed_at = NOW() FROM products p
+              WHERE p.id = m.product_id AND p.status = 'published' AND m.search_id = ANY($1) AND m.emailed_at IS NULL AND m.matched_at <= $2`
+	if _, err := tx.Exec(ctx, query, searchIDs, upTo); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE saved_searches SET last_digest_at = NOW() WHERE id = ANY($1)`, searchIDs); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,65 @@
+package database
+
+import (
+	"context"
+
+	"github.com/LocalLink/internal/models"
+)
+
+// Session Methods
+func (s *Store) CreateSession(ctx context.Context, session *models.Session) error {
+	query := `INSERT INTO user_sessions (id, user_id, device, ip_address, user_agent, expires_at)
+              VALUES ($1, $2, $3, $4, $5, $6) RETURNING created_at, last_seen_at`
+	return s.db.QueryRow(ctx, query, session.ID, session.UserID, session.Device, session.IPAddress, session.UserAgent, session.ExpiresAt).Scan(&session.CreatedAt, &session.LastSeenAt)
+}
+
+// TouchSession reports whether the session is active, refreshing last_seen_at at most once a minute.
+func (s *Store) TouchSession(ctx context.Context, sessionID string, userID int) (bool, error) {
+	query := `WITH active AS (
+                  SELECT id FROM user_sessions
+                  WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL AND expires_at > NOW()
+              ), touched AS (
+                  UPDATE user_sessions SET last_seen_at = NOW()
+                  WHERE id IN (SELECT id FROM active) AND last_seen_at < NOW() - INTERVAL '1 minute'
+              )
+              SELECT COUNT(*) FROM active`
+	var count int
+	err := s.db.QueryRow(ctx, query, sessionID, userID).Scan(&count)
+	return count > 0, err
+}
+
+func (s *Store) GetActiveSessionsForUser(ctx context.Context, userID int) ([]models.Session, error) {
+	query := `SELECT id, user_id, device, ip_address, user_agent, created_at, last_seen_at, expires_at FROM user_sessions
+              WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW() ORDER BY last_seen_at DESC`
+	rows, err := s.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []models.Session
+	for rows.Next() {
+		var session models.Session
+		if err := rows.Scan(&session.ID, &session.UserID, &session.Device, &session.IPAddress, &session.UserAgent, &session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, nil
+}
+
+// RevokeSession terminates one of the user's sessions and reports whether it was active.
+func (s *Store) RevokeSession(ctx context.Context, userID int, sessionID string) (bool, error) {
+	query := `UPDATE user_sessions SET revoked_at = NOW() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`
+	tag, err := s.db.Exec(ctx, query, sessionID, userID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (s *Store) RevokeOtherSessions(ctx context.Context, userID int, keepSessionID string) error {
+	query := `UPDATE user_sessions SET revoked_at = NOW() WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL`
+	_, err := s.db.Exec(ctx, query, userID, keepSessionID)
+	return err
+}
@@ -0,0 +1,170 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/LocalLink/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrSlotUnavailable = errors.New("time slot is full, has passed, or is not offered for this pickup or delivery choice")
+	ErrSlotBooked      = errors.New("time slot already has bookings")
+	ErrOrderClosed     = errors.New("order can no longer be rescheduled")
+	ErrNoOpenSlot      = errors.New("no time slot with room is left at the pickup point that day")
+)
+
+// Time Slot Methods
+func (s *Store) CreateTimeSlot(ctx context.Context, slot *models.TimeSlot) error {
+	query := `INSERT INTO time_slots (producer_id, pickup_point_id, delivery_option_id, starts_at, ends_at, capacity)
+              VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, booked, created_at`
+	return s.db.QueryRow(ctx, query, slot.ProducerID, slot.PickupPointID, slot.DeliveryOptionID, slot.StartsAt, slot.EndsAt, slot.Capacity).
+		Scan(&slot.ID, &slot.Booked, &slot.CreatedAt)
+}
+
+const timeSlotColumns = `id, producer_id, pickup_point_id, delivery_option_id, starts_at, ends_at, capacity, booked, created_at`
+
+func scanTimeSlot(row pgx.Row, t *models.TimeSlot) error {
+	return row.Scan(&t.ID, &t.ProducerID, &t.PickupPointID, &t.DeliveryOptionID, &t.StartsAt, &t.EndsAt, &t.Capacity, &t.Booked, &t.CreatedAt)
+}
+
+func (s *Store) GetTimeSlot(ctx context.Context, slotID int) (*models.TimeSlot, error) {
+	var t models.TimeSlot
+	err := scanTimeSlot(s.db.QueryRow(ctx, `SELECT `+timeSlotColumns+` FROM time_slots WHERE id = $1`, slotID), &t)
+	return &t, err
+}
+
+func (s *Store) GetPickupPointSlots(ctx context.Context, pointID int) ([]models.TimeSlot, error) {
+	return s.getUpcomingSlots(ctx, `pickup_point_id = $1`, pointID)
+}
+
+func (s *Store) GetDeliveryOptionSlots(ctx context.Context, optionID int) ([]models.TimeSlot, error) {
+	return s.getUpcomingSlots(ctx, `delivery_option_id = $1`, optionID)
+}
+
+func (s *Store) getUpcomingSlots(ctx context.Context, where string, id int) ([]models.TimeSlot, error) {
+	query := `SELECT ` + timeSlotColumns + ` FROM time_slots WHERE ` + where + ` AND starts_at > NOW() ORDER BY starts_at`
+	rows, err := s.db.Query(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	slots := []models.TimeSlot{}
+	for rows.Next() {
+		var t models.TimeSlot
+		if err := scanTimeSlot(rows, &t); err != nil {
+			return nil, err
+		}
+		slots = append(slots, t)
+	}
+	return slots, rows.Err()
+}
+
+// DeleteTimeSlot removes a slot nobody has booked yet. Booked slots must be kept so
+// their orders can be rescheduled out of them first.
+func (s *Store) DeleteTimeSlot(ctx context.Context, slotID int) error {
+	tag, err := s.db.Exec(ctx, `DELETE FROM time_slots WHERE id = $1 AND booked = 0`, slotID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrSlotBooked
+	}
+	return nil
+}
+
+// FirstOpenSlot returns the earliest slot with room at a pickup point on the given
+// day. It returns nil if the pickup point doesn't use time slots, and ErrNoOpenSlot
+// if it does but has no room left that day. Days are UTC dates, as pickup dates are.
+func (s *Store) FirstOpenSlot(ctx context.Context, pointID int, day time.Time) (*int, error) {
+	var slotID *int
+	var usesSlots bool
+	query := `SELECT (SELECT id FROM time_slots WHERE pickup_point_id = $1 AND (starts_at AT TIME ZONE 'UTC')::date = $2::date
+                      AND starts_at > NOW() AND booked < capacity ORDER BY starts_at LIMIT 1),
+              EXISTS (SELECT 1 FROM time_slots WHERE pickup_point_id = $1 AND starts_at > NOW())`
+	if err := s.db.QueryRow(ctx, query, pointID, day.Format("2006-01-02")).Scan(&slotID, &usesSlots); err != nil {
+		return nil, err
+	}
+	if slotID == nil && usesSlots {
+		return nil, ErrNoOpenSlot
+	}
+	return slotID, nil
+}
+
+// RescheduleOrder moves an open order into another slot of the same pickup point or
+// delivery option, freeing its old slot.
+func (s *Store) RescheduleOrder(ctx context.Context, orderID, slotID int) (*models.Order, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var status string
+	var fulfilment *models.Fulfilment
+	if err := tx.QueryRow(ctx, `SELECT status, fulfilment FROM orders WHERE id = $1 FOR UPDATE`, orderID).Scan(&status, &fulfilment); err != nil {
+		return nil, err
+	}
+	if (status != models.OrderPending && status != models.OrderPreordered) || fulfilment == nil {
+		return nil, ErrOrderClosed
+	}
+	oldSlotID := fulfilment.SlotID
+	if oldSlotID != nil && *oldSlotID == slotID {
+		return s.GetOrderByID(ctx, orderID)
+	}
+	if err := bookSlot(ctx, tx, &slotID, fulfilment); err != nil {
+		return nil, err
+	}
+	if oldSlotID != nil {
+		if err := releaseSlot(ctx, tx, *oldSlotID); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := tx.Exec(ctx, `UPDATE orders SET slot_id = $1, fulfilment = $2 WHERE id = $3`, slotID, fulfilment, orderID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return s.GetOrderByID(ctx, orderID)
+}
+
+// bookSlot takes one place in a slot of the chosen pickup point or delivery option and
+// records it on the fulfilment. The capacity check and decrement are a single UPDATE,
+// so concurrent checkouts cannot overbook. A slot must be chosen whenever the choice
+// has upcoming slots.
+func bookSlot(ctx context.Context, tx pgx.Tx, slotID *int, f *models.Fulfilment) error {
+	if slotID == nil {
+		var hasSlots bool
+		query := `SELECT EXISTS (SELECT 1 FROM time_slots WHERE (pickup_point_id = $1 OR delivery_option_id = $2) AND starts_at > NOW())`
+		if err := tx.QueryRow(ctx, query, f.PickupPointID, f.DeliveryOptionID).Scan(&hasSlots); err != nil {
+			return err
+		}
+		if hasSlots {
+			return fulfilmentErrorf("choose a time slot for %s", f.Name)
+		}
+		return nil
+	}
+	var startsAt, endsAt time.Time
+	query := `UPDATE time_slots SET booked = booked + 1
+              WHERE id = $1 AND (pickup_point_id = $2 OR delivery_option_id = $3) AND starts_at > NOW() AND booked < capacity
+              RETURNING starts_at, ends_at`
+	err := tx.QueryRow(ctx, query, *slotID, f.PickupPointID, f.DeliveryOptionID).Scan(&startsAt, &endsAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrSlotUnavailable
+	}
+	if err != nil {
+		return err
+	}
+	f.SlotID, f.SlotStartsAt, f.SlotEndsAt = slotID, &startsAt, &endsAt
+	return nil
+}
+
+func releaseSlot(ctx context.Context, tx pgx.Tx, slotID int) error {
+	_, err := tx.Exec(ctx, `UPDATE time_slots SET booked = GREATEST(booked - 1, 0) WHERE id = $1`, slotID)
+	return err
+}
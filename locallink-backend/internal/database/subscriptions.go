@@ -0,0 +1,291 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/LocalLink/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// SubscriptionLeadDays is how far ahead of a pickup its order is generated, so the
+// producer has time to pack it.
+const SubscriptionLeadDays = 2
+
+// ErrPickupHandled is returned when a subscription's pickup was already generated or
+// skipped by the time it was locked.
+var ErrPickupHandled = errors.New("subscription pickup was already handled")
+
+// Subscription Methods
+func (s *Store) CreateSubscriptionPlan(ctx context.Context, plan *models.SubscriptionPlan) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `INSERT INTO subscription_plans (producer_id, name, description, price, cadence, pickup_day, pickup_point_id)
+              VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, active, created_at`
+	err = tx.QueryRow(ctx, query, plan.ProducerID, plan.Name, plan.Description, plan.Price, plan.Cadence, plan.PickupDay, plan.PickupPointID).Scan(&plan.ID, &plan.Active, &plan.CreatedAt)
+	if err != nil {
+		return err
+	}
+	for _, item := range plan.Items {
+		itemQuery := `INSERT INTO subscription_plan_items (plan_id, product_id, variant_id, quantity) VALUES ($1, $2, $3, $4)`
+		if _, err := tx.Exec(ctx, itemQuery, plan.ID, item.ProductID, item.VariantID, item.Quantity); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func (s *Store) GetSubscriptionPlan(ctx context.Context, planID int) (*models.SubscriptionPlan, error) {
+	var plan models.SubscriptionPlan
+	query := `SELECT id, producer_id, name, description, price, cadence, pickup_day, pickup_point_id, active, created_at FROM subscription_plans WHERE id = $1`
+	err := s.db.QueryRow(ctx, query, planID).Scan(&plan.ID, &plan.ProducerID, &plan.Name, &plan.Description, &plan.Price, &plan.Cadence, &plan.PickupDay, &plan.PickupPointID, &plan.Active, &plan.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.db.Query(ctx, `SELECT product_id, variant_id, quantity FROM subscription_plan_items WHERE plan_id = $1 ORDER BY id`, planID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var item models.OrderItemInput
+		if err := rows.Scan(&item.ProductID, &item.VariantID, &item.Quantity); err != nil {
+			return nil, err
+		}
+		plan.Items = append(plan.Items, item)
+	}
+	return &plan, rows.Err()
+}
+
+func (s *Store) GetSubscriptionPlansByProducer(ctx context.Context, producerID int) ([]models.SubscriptionPlan, error) {
+	rows, err := s.db.Query(ctx, `SELECT id FROM subscription_plans WHERE producer_id = $1 AND active ORDER BY created_at`, producerID)
+	if err != nil {
+		return nil, err
+	}
+	var planIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		planIDs = append(planIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	plans := []models.SubscriptionPlan{}
+	for _, id := range planIDs {
+		plan, err := s.GetSubscriptionPlan(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, *plan)
+	}
+	return plans, nil
+}
+
+func (s *Store) UpdateSubscriptionPlan(ctx context.Context, planID int, input models.UpdateSubscriptionPlanInput) (*models.SubscriptionPlan, error) {
+	query := `UPDATE subscription_plans SET name = COALESCE($1, name), description = COALESCE($2, description), price = COALESCE($3, price),
+              pickup_point_id = COALESCE($4, pickup_point_id) WHERE id = $5`
+	if _, err := s.db.Exec(ctx, query, input.Name, input.Description, input.Price, input.PickupPointID, planID); err != nil {
+		return nil, err
+	}
+	return s.GetSubscriptionPlan(ctx, planID)
+}
+
+// DeactivateSubscriptionPlan stops new sign-ups. Existing subscriptions keep running
+// until their buyers cancel.
+func (s *Store) DeactivateSubscriptionPlan(ctx context.Context, planID int) error {
+	_, err := s.db.Exec(ctx, `UPDATE subscription_plans SET active = FALSE WHERE id = $1`, planID)
+	return err
+}
+
+func (s *Store) CreateSubscription(ctx context.Context, plan *models.SubscriptionPlan, buyerID int) (*models.Subscription, error) {
+	sub := models.Subscription{PlanID: plan.ID, BuyerID: buyerID, Status: models.SubscriptionActive}
+	sub.NextPickupOn = FirstPickup(time.Now(), plan.PickupDay)
+	query := `INSERT INTO subscriptions (plan_id, buyer_id, next_pickup_on) VALUES ($1, $2, $3) RETURNING id, created_at`
+	if err := s.db.QueryRow(ctx, query, sub.PlanID, sub.BuyerID, sub.NextPickupOn).Scan(&sub.ID, &sub.CreatedAt); err != nil {
+		return nil, err
+	}
+	sub.Plan = plan
+	return &sub, nil
+}
+
+const subscriptionColumns = `id, plan_id, buyer_id, status, next_pickup_on, created_at`
+
+func scanSubscription(row pgx.Row, sub *models.Subscription) error {
+	return row.Scan(&sub.ID, &sub.PlanID, &sub.BuyerID, &sub.Status, &sub.NextPickupOn, &sub.CreatedAt)
+}
+
+func (s *Store) GetSubscription(ctx context.Context, subscriptionID int) (*models.Subscription, error) {
+	var sub models.Subscription
+	query := `SELECT ` + subscriptionColumns + ` FROM subscriptions WHERE id = $1`
+	if err := scanSubscription(s.db.QueryRow(ctx, query, subscriptionID), &sub); err != nil {
+		return nil, err
+	}
+	plan, err := s.GetSubscriptionPlan(ctx, sub.PlanID)
+	sub.Plan = plan
+	return &sub, err
+}
+
+func (s *Store) GetSubscriptionsForBuyer(ctx context.Context, buyerID int) ([]models.Subscription, error) {
+	rows, err := s.db.Query(ctx, `SELECT id FROM subscriptions WHERE buyer_id = $1 ORDER BY created_at DESC`, buyerID)
+	if err != nil {
+		return nil, err
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	subs := []models.Subscription{}
+	for _, id := range ids {
+		sub, err := s.GetSubscription(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, *sub)
+	}
+	return subs, nil
+}
+
+// UpdateSubscription saves a new status and next pickup date.
+func (s *Store) UpdateSubscription(ctx context.Context, sub *models.Subscription) error {
+	query := `UPDATE subscriptions SET status = $1, next_pickup_on = $2,
+              cancelled_at = CASE WHEN $1 = 'cancelled' THEN COALESCE(cancelled_at, NOW()) END WHERE id = $3`
+	_, err := s.db.Exec(ctx, query, sub.Status, sub.NextPickupOn, sub.ID)
+	return err
+}
+
+// DueSubscription returns the active subscription with the earliest pickup inside
+// the lead time. It returns pgx.ErrNoRows when nothing is due.
+func (s *Store) DueSubscription(ctx context.Context) (*models.Subscription, error) {
+	var sub models.Subscription
+	query := `SELECT ` + subscriptionColumns + ` FROM subscriptions
+              WHERE status = 'active' AND next_pickup_on <= CURRENT_DATE + $1::int
+              ORDER BY next_pickup_on, id LIMIT 1`
+	if err := scanSubscription(s.db.QueryRow(ctx, query, SubscriptionLeadDays), &sub); err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// CreateSubscriptionOrder generates the order for the subscription's next pickup and
+// moves it on to the following pickup in the same transaction, so a pickup is never
+// passed over without its order or a recorded reason. It returns ErrPickupHandled
+// when another run got to the pickup first.
+func (s *Store) CreateSubscriptionOrder(ctx context.Context, sub *models.Subscription, input models.CreateOrderInput) (*models.Order, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	pickupOn := sub.NextPickupOn
+	if err := advanceSubscription(ctx, tx, sub); err != nil {
+		return nil, err
+	}
+	orderID, err := createOrder(ctx, tx, input, sub.BuyerID)
+	if err != nil {
+		return nil, err
+	}
+	if err := recordSubscriptionRun(ctx, tx, sub.ID, pickupOn, &orderID, nil); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return s.GetOrderByID(ctx, orderID)
+}
+
+// SkipSubscriptionPickup moves the subscription past a pickup whose order could not be
+// generated, noting why.
+func (s *Store) SkipSubscriptionPickup(ctx context.Context, sub *models.Subscription, runErr error) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	pickupOn := sub.NextPickupOn
+	if err := advanceSubscription(ctx, tx, sub); err != nil {
+		return err
+	}
+	if err := recordSubscriptionRun(ctx, tx, sub.ID, pickupOn, nil, runErr); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func advanceSubscription(ctx context.Context, tx pgx.Tx, sub *models.Subscription) error {
+	var cadence string
+	var pickupDay int
+	query := `SELECT p.cadence, p.pickup_day FROM subscriptions s JOIN subscription_plans p ON p.id = s.plan_id
+              WHERE s.id = $1 AND s.status = 'active' AND s.next_pickup_on = $2 FOR UPDATE OF s`
+	err := tx.QueryRow(ctx, query, sub.ID, sub.NextPickupOn).Scan(&cadence, &pickupDay)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrPickupHandled
+	}
+	if err != nil {
+		return err
+	}
+	sub.NextPickupOn = NextPickup(sub.NextPickupOn, cadence, pickupDay)
+	_, err = tx.Exec(ctx, `UPDATE subscriptions SET next_pickup_on = $1 WHERE id = $2`, sub.NextPickupOn, sub.ID)
+	return err
+}
+
+// recordSubscriptionRun notes the order generated for a pickup, or why none was.
+func recordSubscriptionRun(ctx context.Context, tx pgx.Tx, subscriptionID int, pickupOn time.Time, orderID *int, runErr error) error {
+	var message *string
+	if runErr != nil {
+		msg := runErr.Error()
+		message = &msg
+	}
+	query := `INSERT INTO subscription_runs (subscription_id, pickup_on, order_id, error) VALUES ($1, $2, $3, $4)
+              ON CONFLICT (subscription_id, pickup_on) DO NOTHING`
+	_, err := tx.Exec(ctx, query, subscriptionID, pickupOn, orderID, message)
+	return err
+}
+
+// FirstPickup returns the earliest pickup day that still leaves the lead time to
+// prepare the order.
+func FirstPickup(now time.Time, pickupDay int) time.Time {
+	day := truncateToDate(now).AddDate(0, 0, SubscriptionLeadDays)
+	return day.AddDate(0, 0, (pickupDay-int(day.Weekday())+7)%7)
+}
+
+// NextPickup returns the pickup after the given one. Monthly plans keep to the same
+// weekday, landing on the first one at least a calendar month later.
+func NextPickup(pickupOn time.Time, cadence string, pickupDay int) time.Time {
+	switch cadence {
+	case "weekly":
+		return pickupOn.AddDate(0, 0, 7)
+	case "fortnightly":
+		return pickupOn.AddDate(0, 0, 14)
+	}
+	day := pickupOn.AddDate(0, 1, 0)
+	return day.AddDate(0, 0, (pickupDay-int(day.Weekday())+7)%7)
+}
+
+func truncateToDate(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,203 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/LocalLink/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrInsufficientStock is returned when an order asks for more than is in stock or
+// left to pre-order.
+var ErrInsufficientStock = errors.New("not enough stock")
+
+// ItemError is returned for an order item the buyer has to change, such as a product
+// that is not on sale or a quantity that is not a multiple of its step.
+type ItemError struct {
+	msg string
+}
+
+func (e *ItemError) Error() string { return e.msg }
+
+func itemErrorf(format string, args ...any) error {
+	return &ItemError{msg: fmt.Sprintf(format, args...)}
+}
+
+// Product Variant Methods
+func (s *Store) CreateProductVariant(ctx context.Context, variant *models.ProductVariant) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if err := insertVariant(ctx, tx, variant); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (s *Store) GetProductVariant(ctx context.Context, variantID int) (*models.ProductVariant, error) {
+	var v models.ProductVariant
+	query := `SELECT id, product_id, name, price, quantity, created_at FROM product_variants WHERE id = $1`
+	err := s.db.QueryRow(ctx, query, variantID).Scan(&v.ID, &v.ProductID, &v.Name, &v.Price, &v.Quantity, &v.CreatedAt)
+	return &v, err
+}
+
+func (s *Store) UpdateProductVariant(ctx context.Context, variantID int, input models.ProductVariantInput) (*models.ProductVariant, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var productID int
+	var oldPrice float64
+	if err := tx.QueryRow(ctx, `SELECT product_id, price FROM product_variants WHERE id = $1 FOR UPDATE`, variantID).Scan(&productID, &oldPrice); err != nil {
+		return nil, err
+	}
+	query := `UPDATE product_variants SET name = COALESCE($1, name), price = COALESCE($2, price), quantity = COALESCE($3, quantity) WHERE id = $4`
+	if _, err := tx.Exec(ctx, query, input.Name, input.Price, input.Quantity, variantID); err != nil {
+		return nil, err
+	}
+	if input.Price != nil && *input.Price != oldPrice {
+		if err := recordPrice(ctx, tx, productID, &variantID, *input.Price); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return s.GetProductVariant(ctx, variantID)
+}
+
+func (s *Store) DeleteProductVariant(ctx context.Context, variantID int) error {
+	_, err := s.db.Exec(ctx, `DELETE FROM product_variants WHERE id = $1`, variantID)
+	return err
+}
+
+func insertVariant(ctx context.Context, tx pgx.Tx, variant *models.ProductVariant) error {
+	query := `INSERT INTO product_variants (product_id, name, price, quantity) VALUES ($1, $2, $3, $4) RETURNING id, created_at`
+	if err := tx.QueryRow(ctx, query, variant.ProductID, variant.Name, variant.Price, variant.Quantity).Scan(&variant.ID, &variant.CreatedAt); err != nil {
+		return err
+	}
+	return recordPrice(ctx, tx, variant.ProductID, &variant.ID, variant.Price)
+}
+
+// attachVariants loads the variants of each product in one query.
+func (s *Store) attachVariants(ctx context.Context, products []models.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+	ids := make([]int, len(products))
+	for i, p := range products {
+		ids[i] = p.ID
+	}
+	query := `SELECT id, product_id, name, price, quantity, created_at FROM product_variants WHERE product_id = ANY($1) ORDER BY product_id, price`
+	rows, err := s.db.Query(ctx, query, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	variantsByProduct := make(map[int][]models.ProductVariant)
+	for rows.Next() {
+		var v models.ProductVariant
+		if err := rows.Scan(&v.ID, &v.ProductID, &v.Name, &v.Price, &v.Quantity, &v.CreatedAt); err != nil {
+			return err
+		}
+		variantsByProduct[v.ProductID] = append(variantsByProduct[v.ProductID], v)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for i := range products {
+		products[i].Variants = variantsByProduct[products[i].ID]
+	}
+	return nil
+}
+
+// reserveStock locks the ordered product (or its chosen variant), checks that it is
+// published, checks the quantity against the unit's step size and the stock, decrements
+// the stock (oldest batch first for perishables), and returns the order item with the
+// product's current name, unit and marked-down price snapshotted. Products that have
+// variants can only be ordered through one of them.
+func reserveStock(ctx context.Context, tx pgx.Tx, input models.OrderItemInput) (models.OrderItem, error) {
+	item := models.OrderItem{ProductID: input.ProductID, VariantID: input.VariantID, Quantity: input.Quantity}
+	if input.Quantity <= 0 {
+		return item, itemErrorf("quantity for product ID %d must be positive", input.ProductID)
+	}
+	var stock, step float64
+	var status string
+	var hasVariants bool
+	var markdowns []models.Markdown
+	var preorderLimit *float64
+	var preordered float64
+	query := `SELECT name, unit, price, quantity, quantity_step, status, markdowns, EXISTS (SELECT 1 FROM product_variants v WHERE v.product_id = p.id),
+              available_from IS NOT NULL AND released_at IS NULL, preorder_limit, preordered_quantity
+              FROM products p WHERE id = $1 FOR UPDATE`
+	err := tx.QueryRow(ctx, query, input.ProductID).Scan(&item.ProductName, &item.Unit, &item.Price, &stock, &step, &status, &markdowns, &hasVariants,
+		&item.Preorder, &preorderLimit, &preordered)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return item, itemErrorf("product ID %d not found", input.ProductID)
+	}
+	if err != nil {
+		return item, err
+	}
+	if status != models.ProductStatusPublished {
+		return item, itemErrorf("product ID %d is not available", input.ProductID)
+	}
+	if !IsQuantityStep(input.Quantity, step) {
+		return item, itemErrorf("quantity for product ID %d must be a multiple of %g", input.ProductID, step)
+	}
+
+	// Pre-orders count against the pre-order limit; stock is taken when the harvest is released.
+	if item.Preorder {
+		if preorderLimit != nil && preordered+input.Quantity > *preorderLimit {
+			return item, fmt.Errorf("%w: only %g left to pre-order for product ID %d", ErrInsufficientStock, *preorderLimit-preordered, input.ProductID)
+		}
+		_, err := tx.Exec(ctx, `UPDATE products SET preordered_quantity = preordered_quantity + $1 WHERE id = $2`, input.Quantity, input.ProductID)
+		return item, err
+	}
+
+	if input.VariantID == nil {
+		if hasVariants {
+			return item, itemErrorf("product ID %d requires choosing a variant", input.ProductID)
+		}
+		if stock < input.Quantity {
+			return item, fmt.Errorf("%w for product ID %d", ErrInsufficientStock, input.ProductID)
+		}
+		if item.Batches, item.Price, err = takeFromBatches(ctx, tx, input.ProductID, input.Quantity, item.Price, markdowns); err != nil {
+			return item, err
+		}
+		_, err = tx.Exec(ctx, `UPDATE products SET quantity = quantity - $1 WHERE id = $2`, input.Quantity, input.ProductID)
+		return item, err
+	}
+
+	query = `SELECT name, price, quantity FROM product_variants WHERE id = $1 AND product_id = $2 FOR UPDATE`
+	err = tx.QueryRow(ctx, query, *input.VariantID, input.ProductID).Scan(&item.VariantName, &item.Price, &stock)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return item, itemErrorf("variant %d not found for product ID %d", *input.VariantID, input.ProductID)
+	}
+	if err != nil {
+		return item, err
+	}
+	if stock < input.Quantity {
+		return item, fmt.Errorf("%w for variant %d of product ID %d", ErrInsufficientStock, *input.VariantID, input.ProductID)
+	}
+	_, err = tx.Exec(ctx, `UPDATE product_variants SET quantity = quantity - $1 WHERE id = $2`, input.Quantity, *input.VariantID)
+	return item, err
+}
+
+// IsQuantityStep reports whether quantity is a whole multiple of step, allowing for
+// floating point error.
+func IsQuantityStep(quantity, step float64) bool {
+	if step <= 0 {
+		return false
+	}
+	n := quantity / step
+	return math.Abs(n-math.Round(n)) < 1e-6
+}
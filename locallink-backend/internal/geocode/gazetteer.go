@@ -0,0 +1,203 @@
+package geocode
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	// cellDegrees is the size of the grid cells used to index entries for reverse
+	// lookups.
+	cellDegrees = 0.1
+	// maxReverseRings bounds how far a reverse lookup searches, in cells.
+	maxReverseRings = 10
+	// maxWindowWords is the longest run of words tried as a key, enough for
+	// "LS1 4DY" or "Hebden Bridge".
+	maxWindowWords = 3
+)
+
+// Gazetteer is a Geocoder over a table of named points, such as postcode centroids
+// or place names, held in memory.
+type Gazetteer struct {
+	entries []Result
+	byKey   map[string]int
+	cells   map[[2]int][]int
+}
+
+// OpenGazetteer loads a gazetteer CSV file; see ReadGazetteer.
+func OpenGazetteer(path string) (*Gazetteer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadGazetteer(f)
+}
+
+// ReadGazetteer reads a CSV with a header row naming a key column ("postcode", "name"
+// or "key"), "latitude" (or "lat"), "longitude" (or "lon"/"lng") and an optional
+// "label" shown in results, defaulting to the key. Keys match ignoring case, spaces
+// and punctuation.
+func ReadGazetteer(r io.Reader) (*Gazetteer, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("gazetteer: reading header: %w", err)
+	}
+	col := map[string]int{"key": -1, "lat": -1, "lon": -1, "label": -1}
+	for i, name := range header {
+		switch strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))) {
+		case "postcode", "name", "key":
+			col["key"] = i
+		case "latitude", "lat":
+			col["lat"] = i
+		case "longitude", "lon", "lng":
+			col["lon"] = i
+		case "label":
+			col["label"] = i
+		}
+	}
+	if col["key"] < 0 || col["lat"] < 0 || col["lon"] < 0 {
+		return nil, errors.New("gazetteer: header needs postcode (or name), latitude and longitude columns")
+	}
+
+	g := &Gazetteer{byKey: make(map[string]int), cells: make(map[[2]int][]int)}
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("gazetteer: line %d: %w", line, err)
+		}
+		field := func(name string) string {
+			if i := col[name]; i >= 0 && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		key := normalize(field("key"))
+		if key == "" {
+			continue
+		}
+		lat, err1 := strconv.ParseFloat(field("lat"), 64)
+		lon, err2 := strconv.ParseFloat(field("lon"), 64)
+		if err1 != nil || err2 != nil || lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+			return nil, fmt.Errorf("gazetteer: line %d: invalid coordinates", line)
+		}
+		label := field("label")
+		if label == "" {
+			label = field("key")
+		}
+		if _, dup := g.byKey[key]; dup {
+			continue
+		}
+		i := len(g.entries)
+		g.entries = append(g.entries, Result{Label: label, Latitude: lat, Longitude: lon})
+		g.byKey[key] = i
+		cell := cellOf(lat, lon)
+		g.cells[cell] = append(g.cells[cell], i)
+	}
+	return g, nil
+}
+
+// Len reports how many places the gazetteer holds.
+func (g *Gazetteer) Len() int {
+	return len(g.entries)
+}
+
+// Geocode looks the address up whole, then each comma-separated part and each run of
+// up to three words within a part. The longest matching key wins, so a postcode is
+// preferred over the town it is in; ties go to the match nearer the end.
+func (g *Gazetteer) Geocode(_ context.Context, address string) (Result, error) {
+	if i, ok := g.byKey[normalize(address)]; ok {
+		return g.entries[i], nil
+	}
+	best, bestLen := -1, 0
+	for _, part := range strings.Split(address, ",") {
+		words := strings.Fields(part)
+		for start := range words {
+			for n := 1; n <= maxWindowWords && start+n <= len(words); n++ {
+				key := normalize(strings.Join(words[start:start+n], ""))
+				if i, ok := g.byKey[key]; ok && len(key) >= bestLen {
+					best, bestLen = i, len(key)
+				}
+			}
+		}
+	}
+	if best < 0 {
+		return Result{}, ErrNotFound
+	}
+	return g.entries[best], nil
+}
+
+// Reverse returns the nearest entry, searching outward ring by ring through the grid
+// until no unsearched cell can hold anything closer.
+func (g *Gazetteer) Reverse(_ context.Context, lat, lon float64) (Result, error) {
+	center := cellOf(lat, lon)
+	// The narrowest side of a cell here, in metres; cells shrink east-west towards the poles.
+	cellMeters := cellDegrees * 111320 * math.Max(math.Cos(lat*math.Pi/180), 0.01)
+	best, bestDist := -1, math.Inf(1)
+	for ring := 0; ring <= maxReverseRings; ring++ {
+		for dy := -ring; dy <= ring; dy++ {
+			for dx := -ring; dx <= ring; dx++ {
+				if max(abs(dx), abs(dy)) != ring {
+					continue
+				}
+				for _, i := range g.cells[[2]int{center[0] + dy, center[1] + dx}] {
+					if d := distance(lat, lon, g.entries[i].Latitude, g.entries[i].Longitude); d < bestDist {
+						best, bestDist = i, d
+					}
+				}
+			}
+		}
+		if best >= 0 && float64(ring)*cellMeters >= bestDist {
+			break
+		}
+	}
+	if best < 0 {
+		return Result{}, ErrNotFound
+	}
+	return g.entries[best], nil
+}
+
+func normalize(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func cellOf(lat, lon float64) [2]int {
+	return [2]int{int(math.Floor(lat / cellDegrees)), int(math.Floor(lon / cellDegrees))}
+}
+
+// distance is the great-circle distance in metres.
+func distance(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadius = 6371008.8
+	toRad := math.Pi / 180
+	dLat := (lat2 - lat1) * toRad
+	dLon := (lon2 - lon1) * toRad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1*toRad)*math.Cos(lat2*toRad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(a))
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,107 @@
+package geocode
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const testGazetteer = `postcode,latitude,longitude,label
+LS1 4DY,53.7997,-1.5492,"LS1 4DY, Leeds"
+HX7 8AD,53.7420,-2.0140,
+Hebden Bridge,53.7410,-2.0110,Hebden Bridge
+Leeds,53.8008,-1.5491,Leeds
+`
+
+func TestReadGazetteer(t *testing.T) {
+	tests := []struct {
+		name    string
+		csv     string
+		wantLen int
+		wantErr bool
+	}{
+		{"postcode header", testGazetteer, 4, false},
+		{"short header names", "name,lat,lng\nLeeds,53.8,-1.55\n", 1, false},
+		{"byte order mark", "\ufeffkey,lat,lon\nLeeds,53.8,-1.55\n", 1, false},
+		{"blank keys skipped", "key,lat,lon\n,53.8,-1.55\nLeeds,53.8,-1.55\n", 1, false},
+		{"duplicate keys keep the first", "key,lat,lon\nLeeds,53.8,-1.55\nleeds,0,0\n", 1, false},
+		{"missing coordinate column", "postcode,latitude\nLS1,53.8\n", 0, true},
+		{"unparseable coordinates", "key,lat,lon\nLeeds,north,-1.55\n", 0, true},
+		{"out of range coordinates", "key,lat,lon\nLeeds,91,-1.55\n", 0, true},
+		{"empty file", "", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := ReadGazetteer(strings.NewReader(tt.csv))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadGazetteer error = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && g.Len() != tt.wantLen {
+				t.Errorf("Len() = %d, want %d", g.Len(), tt.wantLen)
+			}
+		})
+	}
+}
+
+func TestGazetteerGeocode(t *testing.T) {
+	g, err := ReadGazetteer(strings.NewReader(testGazetteer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		address string
+		want    string
+	}{
+		{"LS1 4DY", "LS1 4DY, Leeds"},
+		{"ls14dy", "LS1 4DY, Leeds"},
+		{"1 Park Row, Leeds, LS1 4DY", "LS1 4DY, Leeds"},
+		{"Hebden Bridge", "Hebden Bridge"},
+		{"Old Mill, Heptonstall, HX7 8AD", "HX7 8AD"},
+		{"The Market, Leeds", "Leeds"},
+	}
+	for _, tt := range tests {
+		got, err := g.Geocode(context.Background(), tt.address)
+		if err != nil {
+			t.Errorf("Geocode(%q) error: %v", tt.address, err)
+			continue
+		}
+		if got.Label != tt.want {
+			t.Errorf("Geocode(%q) = %q, want %q", tt.address, got.Label, tt.want)
+		}
+	}
+	if _, err := g.Geocode(context.Background(), "Nowhere Lane, Atlantis"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Geocode of an unknown place: error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestGazetteerReverse(t *testing.T) {
+	g, err := ReadGazetteer(strings.NewReader(testGazetteer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		lat, lon float64
+		want     string
+	}{
+		{"exact point", 53.7997, -1.5492, "LS1 4DY, Leeds"},
+		{"nearest of two in a cell", 53.8006, -1.5491, "Leeds"},
+		{"nearest across a cell edge", 53.7419, -2.0139, "HX7 8AD"},
+		{"several cells away", 53.9, -1.7, "Leeds"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := g.Reverse(context.Background(), tt.lat, tt.lon)
+			if err != nil {
+				t.Fatalf("Reverse error: %v", err)
+			}
+			if got.Label != tt.want {
+				t.Errorf("Reverse(%g, %g) = %q, want %q", tt.lat, tt.lon, got.Label, tt.want)
+			}
+		})
+	}
+	if _, err := g.Reverse(context.Background(), -33.87, 151.21); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Reverse far from every entry: error = %v, want ErrNotFound", err)
+	}
+}
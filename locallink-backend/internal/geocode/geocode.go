@@ -0,0 +1,39 @@
+// Package geocode turns addresses and postcodes into coordinates and back, without
+// calling out to an external service.
+package geocode
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrNotFound    = errors.New("no location found")
+	ErrUnavailable = errors.New("geocoding is not configured")
+)
+
+// Result is a resolved place.
+type Result struct {
+	Label     string  `json:"label"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+type Geocoder interface {
+	// Geocode resolves a free-form address or postcode.
+	Geocode(ctx context.Context, address string) (Result, error)
+	// Reverse finds the nearest known place to a coordinate.
+	Reverse(ctx context.Context, lat, lon float64) (Result, error)
+}
+
+// Disabled is the Geocoder used when no dataset is configured; every lookup fails
+// with ErrUnavailable.
+type Disabled struct{}
+
+func (Disabled) Geocode(context.Context, string) (Result, error) {
+	return Result{}, ErrUnavailable
+}
+
+func (Disabled) Reverse(context.Context, float64, float64) (Result, error) {
+	return Result{}, ErrUnavailable
+}
@@ -0,0 +1,100 @@
+// Package geojson holds the small subset of GeoJSON (RFC 7946) the API reads and
+// writes: feature collections of points and polygons in WGS 84 longitude/latitude.
+package geojson
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+type Feature struct {
+	Type       string                 `json:"type"`
+	ID         interface{}            `json:"id,omitempty"`
+	Geometry   json.RawMessage        `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// NewFeatureCollection wraps features in a collection, encoding an empty list as [].
+func NewFeatureCollection(features []Feature) FeatureCollection {
+	if features == nil {
+		features = []Feature{}
+	}
+	return FeatureCollection{Type: "FeatureCollection", Features: features}
+}
+
+func NewFeature(id interface{}, geometry json.RawMessage, properties map[string]interface{}) Feature {
+	if properties == nil {
+		properties = map[string]interface{}{}
+	}
+	return Feature{Type: "Feature", ID: id, Geometry: geometry, Properties: properties}
+}
+
+// Point encodes a point geometry.
+func Point(lon, lat float64) json.RawMessage {
+	data, _ := json.Marshal(map[string]interface{}{"type": "Point", "coordinates": [2]float64{lon, lat}})
+	return data
+}
+
+type geometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// ValidatePolygon checks that a geometry is a Polygon or MultiPolygon with closed
+// rings of at least four in-range positions. Self-intersection is left to PostGIS.
+func ValidatePolygon(raw json.RawMessage) error {
+	var g geometry
+	if err := json.Unmarshal(raw, &g); err != nil {
+		return errors.New("geometry is not valid GeoJSON")
+	}
+	switch g.Type {
+	case "Polygon":
+		var rings [][][]float64
+		if err := json.Unmarshal(g.Coordinates, &rings); err != nil {
+			return errors.New("polygon coordinates must be an array of rings")
+		}
+		return validateRings(rings)
+	case "MultiPolygon":
+		var polygons [][][][]float64
+		if err := json.Unmarshal(g.Coordinates, &polygons); err != nil {
+			return errors.New("multipolygon coordinates must be an array of polygons")
+		}
+		if len(polygons) == 0 {
+			return errors.New("multipolygon has no polygons")
+		}
+		for _, rings := range polygons {
+			if err := validateRings(rings); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("geometry type %q is not supported; use Polygon or MultiPolygon", g.Type)
+}
+
+func validateRings(rings [][][]float64) error {
+	if len(rings) == 0 {
+		return errors.New("polygon has no rings")
+	}
+	for _, ring := range rings {
+		if len(ring) < 4 {
+			return errors.New("polygon rings need at least four positions")
+		}
+		for _, pos := range ring {
+			if len(pos) < 2 || pos[0] < -180 || pos[0] > 180 || pos[1] < -90 || pos[1] > 90 {
+				return errors.New("polygon positions must be [longitude, latitude] within range")
+			}
+		}
+		first, last := ring[0], ring[len(ring)-1]
+		if first[0] != last[0] || first[1] != last[1] {
+			return errors.New("polygon rings must be closed")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package geojson
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestValidatePolygon(t *testing.T) {
+	tests := []struct {
+		name     string
+		geometry string
+		wantErr  bool
+	}{
+		{"polygon", `{"type":"Polygon","coordinates":[[[-1.6,53.7],[-1.5,53.7],[-1.5,53.8],[-1.6,53.7]]]}`, false},
+		{"polygon with a hole", `{"type":"Polygon","coordinates":[
+			[[-1.7,53.6],[-1.4,53.6],[-1.4,53.9],[-1.7,53.9],[-1.7,53.6]],
+			[[-1.6,53.7],[-1.5,53.7],[-1.5,53.8],[-1.6,53.7]]]}`, false},
+		{"multipolygon", `{"type":"MultiPolygon","coordinates":[
+			[[[-1.6,53.7],[-1.5,53.7],[-1.5,53.8],[-1.6,53.7]]],
+			[[[-2.1,53.7],[-2.0,53.7],[-2.0,53.8],[-2.1,53.7]]]]}`, false},
+		{"positions with altitude", `{"type":"Polygon","coordinates":[[[-1.6,53.7,10],[-1.5,53.7,10],[-1.5,53.8,10],[-1.6,53.7,10]]]}`, false},
+
+		{"not JSON", `{"type":`, true},
+		{"point", `{"type":"Point","coordinates":[-1.6,53.7]}`, true},
+		{"no rings", `{"type":"Polygon","coordinates":[]}`, true},
+		{"empty multipolygon", `{"type":"MultiPolygon","coordinates":[]}`, true},
+		{"coordinates of the wrong depth", `{"type":"Polygon","coordinates":[[-1.6,53.7],[-1.5,53.7]]}`, true},
+		{"ring too short", `{"type":"Polygon","coordinates":[[[-1.6,53.7],[-1.5,53.7],[-1.6,53.7]]]}`, true},
+		{"open ring", `{"type":"Polygon","coordinates":[[[-1.6,53.7],[-1.5,53.7],[-1.5,53.8],[-1.6,53.8]]]}`, true},
+		{"longitude out of range", `{"type":"Polygon","coordinates":[[[-181,53.7],[-1.5,53.7],[-1.5,53.8],[-181,53.7]]]}`, true},
+		{"latitude out of range", `{"type":"Polygon","coordinates":[[[-1.6,91],[-1.5,53.7],[-1.5,53.8],[-1.6,91]]]}`, true},
+		{"position missing latitude", `{"type":"Polygon","coordinates":[[[-1.6],[-1.5,53.7],[-1.5,53.8],[-1.6]]]}`, true},
+		{"bad polygon inside a multipolygon", `{"type":"MultiPolygon","coordinates":[
+			[[[-1.6,53.7],[-1.5,53.7],[-1.5,53.8],[-1.6,53.7]]],
+			[[[-2.1,53.7],[-2.0,53.7],[-2.0,53.8],[-2.1,53.8]]]]}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePolygon(json.RawMessage(tt.geometry))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePolygon() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package images
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	_ "image/gif"
+)
+
+const maxPixels = 40_000_000
+
+var ErrUnsupportedType = errors.New("unsupported image type; upload a JPEG, PNG or GIF")
+
+// Variant is one generated rendition of an uploaded image.
+type Variant struct {
+	Name        string
+	ContentType string
+	Extension   string
+	Width       int
+	Height      int
+	Data        []byte
+}
+
+// Sizes are the generated renditions and the longest edge, in pixels, of each.
+var Sizes = []struct {
+	Name    string
+	MaxEdge int
+}{
+	{"thumb", 160},
+	{"small", 480},
+	{"large", 1280},
+}
+
+// SniffContentType detects the image type from the data itself, ignoring whatever
+// the client claimed.
+func SniffContentType(data []byte) (string, error) {
+	contentType := http.DetectContentType(data)
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif":
+		return contentType, nil
+	}
+	return "", ErrUnsupportedType
+}
+
+// Process validates an upload and renders the original plus every size in Sizes.
+// JPEGs stay JPEG; PNGs and GIFs are re-encoded as PNG to keep transparency.
+func Process(data []byte) ([]Variant, error) {
+	contentType, err := SniffContentType(data)
+	if err != nil {
+		return nil, err
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("could not read image: %w", err)
+	}
+	if config.Width*config.Height > maxPixels {
+		return nil, fmt.Errorf("image is too large (%dx%d)", config.Width, config.Height)
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("could not decode image: %w", err)
+	}
+
+	outputType, extension := "image/png", ".png"
+	if contentType == "image/jpeg" {
+		outputType, extension = "image/jpeg", ".jpg"
+	}
+
+	bounds := src.Bounds()
+	variants := []Variant{{
+		Name:        "original",
+		ContentType: contentType,
+		Extension:   extensionFor(contentType),
+		Width:       bounds.Dx(),
+		Height:      bounds.Dy(),
+		Data:        data,
+	}}
+	nrgba := toNRGBA(src)
+	for _, size := range Sizes {
+		resized := fit(nrgba, size.MaxEdge)
+		encoded, err := encode(resized, outputType)
+		if err != nil {
+			return nil, err
+		}
+		variants = append(variants, Variant{
+			Name:        size.Name,
+			ContentType: outputType,
+			Extension:   extension,
+			Width:       resized.Bounds().Dx(),
+			Height:      resized.Bounds().Dy(),
+			Data:        encoded,
+		})
+	}
+	return variants, nil
+}
+
+func extensionFor(contentType string) string {
+	switch contentType {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/gif":
+		return ".gif"
+	}
+	return ".png"
+}
+
+func encode(img image.Image, contentType string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	if contentType == "image/jpeg" {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85})
+	} else {
+		err = png.Encode(&buf, img)
+	}
+	return buf.Bytes(), err
+}
+
+func toNRGBA(src image.Image) *image.NRGBA {
+	if img, ok := src.(*image.NRGBA); ok && img.Rect.Min == (image.Point{}) {
+		return img
+	}
+	bounds := src.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), src, bounds.Min, draw.Src)
+	return dst
+}
+
+// fit scales src down so its longest edge is at most maxEdge, averaging every source
+// pixel that falls under each destination pixel. Smaller images are returned as is.
+func fit(src *image.NRGBA, maxEdge int) *image.NRGBA {
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	if sw <= maxEdge && sh <= maxEdge {
+		return src
+	}
+	dw, dh := maxEdge, sh*maxEdge/sw
+	if sh > sw {
+		dw, dh = sw*maxEdge/sh, maxEdge
+	}
+	if dw < 1 {
+		dw = 1
+	}
+	if dh < 1 {
+		dh = 1
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for dy := 0; dy < dh; dy++ {
+		y0, y1 := dy*sh/dh, (dy+1)*sh/dh
+		if y1 == y0 {
+			y1 = y0 + 1
+		}
+		for dx := 0; dx < dw; dx++ {
+			x0, x1 := dx*sw/dw, (dx+1)*sw/dw
+			if x1 == x0 {
+				x1 = x0 + 1
+			}
+			var r, g, b, a, n uint64
+			for y := y0; y < y1; y++ {
+				row := src.Pix[y*src.Stride:]
+				for x := x0; x < x1; x++ {
+					p := row[x*4 : x*4+4]
+					alpha := uint64(p[3])
+					r += uint64(p[0]) * alpha
+					g += uint64(p[1]) * alpha
+					b += uint64(p[2]) * alpha
+					a += alpha
+					n++
+				}
+			}
+			out := dst.Pix[dy*dst.Stride+dx*4:]
+			if a > 0 {
+				out[0], out[1], out[2] = uint8(r/a), uint8(g/a), uint8(b/a)
+			}
+			out[3] = uint8(a / n)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,35 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/LocalLink/internal/database"
+)
+
+// AccountDeletion erases the personal data of accounts whose deletion grace period has passed.
+func AccountDeletion(store *database.Store) Job {
+	return Job{
+		Name:     "account-deletion",
+		Interval: time.Hour,
+		Run: func(ctx context.Context) error {
+			userIDs, err := store.GetDueDeletions(ctx)
+			if err != nil {
+				return err
+			}
+			count := 0
+			for _, userID := range userIDs {
+				if err := store.AnonymizeUser(ctx, userID); err != nil {
+					log.Printf("account deletion for user %d: %v", userID, err)
+					continue
+				}
+				count++
+			}
+			if count > 0 {
+				log.Printf("Anonymized %d deleted accounts", count)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,24 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/LocalLink/internal/database"
+)
+
+// BatchExpiry withdraws perishable batches from sale once their best-before date passes.
+func BatchExpiry(store *database.Store) Job {
+	return Job{
+		Name:     "batch-expiry",
+		Interval: 15 * time.Minute,
+		Run: func(ctx context.Context) error {
+			count, err := store.ExpireBatches(ctx)
+			if count > 0 {
+				log.Printf("Withdrew expired batches from %d products", count)
+			}
+			return err
+		},
+	}
+}
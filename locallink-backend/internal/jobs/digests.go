@@ -0,0 +1,66 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/LocalLink/internal/database"
+	"github.com/LocalLink/internal/mail"
+	"github.com/LocalLink/internal/models"
+)
+
+// SearchDigests emails each user who asked for it a daily digest of the new listings
+// that matched their saved searches.
+func SearchDigests(store *database.Store, sender mail.Sender) Job {
+	return Job{
+		Name:     "search-digests",
+		Interval: time.Hour,
+		Run: func(ctx context.Context) error {
+			upTo := time.Now()
+			digests, err := store.GetPendingDigests(ctx, upTo)
+			if err != nil {
+				return err
+			}
+			for _, digest := range digests {
+				subject, body := composeDigest(digest)
+				if err := sender.Send(ctx, digest.Email, subject, body); err != nil {
+					log.Printf("search digest for user %d: %v", digest.UserID, err)
+					continue
+				}
+				if err := store.MarkDigestSent(ctx, digest.SearchIDs, upTo); err != nil {
+					return err
+				}
+			}
+			if len(digests) > 0 {
+				log.Printf("Sent %d saved search digests", len(digests))
+			}
+			return nil
+		},
+	}
+}
+
+func composeDigest(digest models.SearchDigest) (subject, body string) {
+	subject = fmt.Sprintf("%d new listings match your saved searches", len(digest.Matches))
+	if len(digest.Matches) == 1 {
+		subject = "A new listing matches your saved search"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Hi %s,\n\nHere is what's new near you since your last digest.\n", digest.Name)
+	search := -1
+	for _, m := range digest.Matches {
+		if m.SearchID != search {
+			search = m.SearchID
+			fmt.Fprintf(&b, "\n%s\n", m.SearchName)
+		}
+		unit := "per " + m.Unit
+		if m.Unit == "each" {
+			unit = "each"
+		}
+		fmt.Fprintf(&b, "  - %s, %.2f %s\n", m.ProductName, m.Price, unit)
+	}
+	b.WriteString("\nYou can change or remove your saved searches in your account at any time.\n")
+	return subject, b.String()
+}
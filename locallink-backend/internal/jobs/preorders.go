@@ -0,0 +1,31 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/LocalLink/internal/database"
+	"github.com/LocalLink/internal/websocket"
+)
+
+// PreorderRelease converts pre-orders into regular orders once their harvest has been
+// released, and tells each buyer the outcome.
+func PreorderRelease(store *database.Store, hub *websocket.Hub) Job {
+	return Job{
+		Name:     "preorder-release",
+		Interval: time.Minute,
+		Run: func(ctx context.Context) error {
+			released, err := store.ReleasePreorders(ctx)
+			for _, release := range released {
+				msg := fmt.Sprintf(`{"type": "order_update", "orderId": %d, "status": "%s"}`, release.OrderID, release.Status)
+				hub.SendToUser(release.BuyerID, []byte(msg))
+			}
+			if len(released) > 0 {
+				log.Printf("Released %d pre-orders", len(released))
+			}
+			return err
+		},
+	}
+}
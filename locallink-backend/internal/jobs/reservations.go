@@ -0,0 +1,24 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/LocalLink/internal/database"
+)
+
+// ReservationSweeper returns stock held by checkout reservations that expired unconfirmed.
+func ReservationSweeper(store *database.Store) Job {
+	return Job{
+		Name:     "reservation-sweeper",
+		Interval: time.Minute,
+		Run: func(ctx context.Context) error {
+			count, err := store.ReleaseExpiredReservations(ctx)
+			if count > 0 {
+				log.Printf("Released %d expired stock reservations", count)
+			}
+			return err
+		},
+	}
+}
@@ -0,0 +1,111 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/LocalLink/internal/database"
+	"github.com/LocalLink/internal/models"
+	"github.com/LocalLink/internal/websocket"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Subscriptions generates the order for every subscription whose next pickup is
+// coming up, through the same order path buyers use, and notifies the buyer and
+// producer.
+func Subscriptions(store *database.Store, hub *websocket.Hub) Job {
+	return Job{
+		Name:     "subscriptions",
+		Interval: time.Hour,
+		Run: func(ctx context.Context) error {
+			count := 0
+			for {
+				sub, err := store.DueSubscription(ctx)
+				if errors.Is(err, pgx.ErrNoRows) {
+					break
+				}
+				if err != nil {
+					return err
+				}
+				if err := runSubscription(ctx, store, hub, sub); err != nil {
+					return err
+				}
+				count++
+			}
+			if count > 0 {
+				log.Printf("Processed %d subscription pickups", count)
+			}
+			return nil
+		},
+	}
+}
+
+// Plans made before pickup points existed, or whose pickup point has since closed,
+// can't generate orders until the producer chooses a pickup point for them.
+var (
+	errNoPickupPoint     = errors.New("the plan has no pickup point; the producer needs to choose one")
+	errPickupPointClosed = errors.New("the plan's pickup point has closed; the producer needs to choose another")
+)
+
+func runSubscription(ctx context.Context, store *database.Store, hub *websocket.Hub, sub *models.Subscription) error {
+	pickupOn := sub.NextPickupOn
+	plan, err := store.GetSubscriptionPlan(ctx, sub.PlanID)
+	if err != nil {
+		return err
+	}
+	var order *models.Order
+	err = checkPlanPickupPoint(ctx, store, plan)
+	if err == nil {
+		input := models.CreateOrderInput{ProducerID: plan.ProducerID, Items: plan.Items, TotalPrice: &plan.Price, PickupPointID: plan.PickupPointID}
+		// Subscribers get the earliest free slot on their pickup day. When the day is
+		// fully booked the pickup is skipped and both sides are told why.
+		input.SlotID, err = store.FirstOpenSlot(ctx, *plan.PickupPointID, pickupOn)
+		if err == nil {
+			order, err = store.CreateSubscriptionOrder(ctx, sub, input)
+		}
+	}
+	if errors.Is(err, database.ErrPickupHandled) {
+		return nil
+	}
+
+	notice := map[string]interface{}{
+		"type":           "subscription_order",
+		"subscriptionId": sub.ID,
+		"pickupOn":       pickupOn.Format("2006-01-02"),
+	}
+	if err != nil {
+		log.Printf("subscription %d: could not create order for %s: %v", sub.ID, pickupOn.Format("2006-01-02"), err)
+		// The pickup is only passed over once the reason is on record; if that
+		// fails it is retried on the next run.
+		if skipErr := store.SkipSubscriptionPickup(ctx, sub, err); skipErr != nil && !errors.Is(skipErr, database.ErrPickupHandled) {
+			return skipErr
+		}
+		notice["type"] = "subscription_order_failed"
+		notice["error"] = err.Error()
+	} else {
+		notice["orderId"] = order.ID
+	}
+
+	msg, _ := json.Marshal(notice)
+	hub.SendToUser(sub.BuyerID, msg)
+	hub.SendToUser(plan.ProducerID, msg)
+	return nil
+}
+
+func checkPlanPickupPoint(ctx context.Context, store *database.Store, plan *models.SubscriptionPlan) error {
+	if plan.PickupPointID == nil {
+		return errNoPickupPoint
+	}
+	point, err := store.GetPickupPoint(ctx, *plan.PickupPointID)
+	if err != nil {
+		return err
+	}
+	if !point.Active {
+		return errPickupPointClosed
+	}
+	return nil
+}
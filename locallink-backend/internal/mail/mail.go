@@ -0,0 +1,62 @@
+// Package mail sends plain-text email notifications.
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	netmail "net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+type Sender interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// SMTPSender delivers mail through an SMTP relay. Username may be empty for relays
+// that don't require authentication.
+type SMTPSender struct {
+	Addr     string
+	Username string
+	Password string
+	From     string
+}
+
+func (s SMTPSender) Send(_ context.Context, to, subject, body string) error {
+	var auth smtp.Auth
+	if s.Username != "" {
+		host, _, err := net.SplitHostPort(s.Addr)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", s.Username, s.Password, host)
+	}
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", s.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", headerSafe(to))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", headerSafe(subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	envelopeFrom := s.From
+	if addr, err := netmail.ParseAddress(s.From); err == nil {
+		envelopeFrom = addr.Address
+	}
+	return smtp.SendMail(s.Addr, auth, envelopeFrom, []string{to}, []byte(msg.String()))
+}
+
+// LogSender writes mail to the log instead of sending it, for development without an
+// SMTP relay.
+type LogSender struct{}
+
+func (LogSender) Send(_ context.Context, to, subject, body string) error {
+	log.Printf("mail to %s: %s\n%s", to, subject, body)
+	return nil
+}
+
+func headerSafe(s string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+}
@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type User struct {
 	ID           int       `json:"id"`
@@ -8,19 +11,212 @@ type User struct {
 	Email        string    `json:"email"`
 	PasswordHash string    `json:"-"`
 	Role         string    `json:"role"`
+	MFAEnabled   bool      `json:"mfaEnabled"`
+	TOTPSecret   string    `json:"-"`
 	CreatedAt    time.Time `json:"createdAt"`
+
+	DeletionScheduledFor *time.Time `json:"deletionScheduledFor,omitempty"`
+	// DefaultLocation is used for nearby searches made without coordinates.
+	DefaultLocation *Location `json:"defaultLocation,omitempty"`
+}
+
+// Location is a saved point. Address, on input, is geocoded when Latitude and
+// Longitude are left out, and becomes the Label.
+type Location struct {
+	Label     string  `json:"label"`
+	Address   string  `json:"address,omitempty"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// ProducerProfile is a producer's public storefront.
+type ProducerProfile struct {
+	ProducerID     int            `json:"producerId"`
+	Name           string         `json:"name"`
+	FarmName       string         `json:"farmName"`
+	Bio            string         `json:"bio"`
+	Practices      []string       `json:"practices"`
+	Certifications []string       `json:"certifications"`
+	OpeningHours   []OpeningHours `json:"openingHours"`
+	PickupAddress  string         `json:"pickupAddress"`
+	Latitude       float64        `json:"latitude"`
+	Longitude      float64        `json:"longitude"`
+	UpdatedAt      time.Time      `json:"updatedAt"`
+
+	DistanceMeters *float64 `json:"distanceMeters,omitempty"`
+}
+
+// OpeningHours are the hours for one weekday (0 for Sunday), as HH:MM.
+type OpeningHours struct {
+	Day    int    `json:"day"`
+	Opens  string `json:"opens"`
+	Closes string `json:"closes"`
+}
+
+type RatingSummary struct {
+	Average float64 `json:"average"`
+	Count   int     `json:"count"`
+}
+
+// ProducerStorefront is the public view of a producer: their profile, what they have
+// on sale, and how buyers rate them.
+type ProducerStorefront struct {
+	ProducerProfile
+	Products []Product     `json:"products"`
+	Rating   RatingSummary `json:"rating"`
 }
 
 type Product struct {
-	ID          int       `json:"id"`
-	ProducerID  int       `json:"producerId"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	Price       float64   `json:"price"`
-	Quantity    int       `json:"quantity"`
-	Latitude    float64   `json:"latitude"`
-	Longitude   float64   `json:"longitude"`
-	CreatedAt   time.Time `json:"createdAt"`
+	ID           int       `json:"id"`
+	ProducerID   int       `json:"producerId"`
+	SKU          string    `json:"sku,omitempty"`
+	Name         string    `json:"name"`
+	Description  string    `json:"description"`
+	Category     string    `json:"category,omitempty"`
+	Price        float64   `json:"price"`
+	Quantity     float64   `json:"quantity"`
+	Unit         string    `json:"unit"`
+	QuantityStep float64   `json:"quantityStep"`
+	Status       string    `json:"status"`
+	Latitude     float64   `json:"latitude"`
+	Longitude    float64   `json:"longitude"`
+	CreatedAt    time.Time `json:"createdAt"`
+
+	// A product with AvailableFrom set is sold as a pre-order, up to PreorderLimit,
+	// until the producer releases the harvest.
+	AvailableFrom      *time.Time `json:"availableFrom,omitempty"`
+	PreorderLimit      *float64   `json:"preorderLimit,omitempty"`
+	PreorderedQuantity float64    `json:"preorderedQuantity,omitempty"`
+	Preorder           bool       `json:"preorder"`
+
+	// BatchTracked products take their stock from dated batches, from the first batch
+	// added onwards, rather than from Quantity directly.
+	BatchTracked bool `json:"batchTracked"`
+
+	// Markdowns are the producer's automatic discounts as best-before approaches.
+	Markdowns []Markdown `json:"markdowns,omitempty"`
+	// DiscountPercent and SalePrice reflect the markdown currently applied to the
+	// oldest batch on sale, if any.
+	DiscountPercent float64  `json:"discountPercent,omitempty"`
+	SalePrice       *float64 `json:"salePrice,omitempty"`
+
+	Images   []ProductImage   `json:"images,omitempty"`
+	Variants []ProductVariant `json:"variants,omitempty"`
+	Batches  []ProductBatch   `json:"batches,omitempty"`
+
+	// DistanceMeters is set on location searches.
+	DistanceMeters *float64 `json:"distanceMeters,omitempty"`
+}
+
+// BoundingBox is a map viewport in WGS 84 degrees.
+type BoundingBox struct {
+	MinLon, MinLat, MaxLon, MaxLat float64
+}
+
+// ProductCluster groups the available products that fall in one grid cell of a map
+// view, placed at their centroid. Product is set when the cell holds only one.
+type ProductCluster struct {
+	Latitude  float64
+	Longitude float64
+	Count     int
+	MinPrice  float64
+	Product   *Product
+}
+
+// Sort orders for nearby product searches.
+const (
+	SortDistance = "distance"
+	SortPrice    = "price"
+	SortNewest   = "newest"
+)
+
+// Markdown takes PercentOff off the price of stock within DaysBefore days of its
+// best-before date.
+type Markdown struct {
+	DaysBefore int     `json:"daysBefore"`
+	PercentOff float64 `json:"percentOff"`
+}
+
+// ProductBatch is a dated lot of a perishable product's stock. Batches are sold oldest
+// best-before first and are withdrawn once the best-before date has passed.
+type ProductBatch struct {
+	ID          int        `json:"id"`
+	ProductID   int        `json:"productId"`
+	Quantity    float64    `json:"quantity"`
+	HarvestedOn *time.Time `json:"harvestedOn,omitempty"`
+	BestBefore  time.Time  `json:"bestBefore"`
+	DaysLeft    int        `json:"daysLeft"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
+
+// BatchAllocation records how much of an order item came from which batch.
+type BatchAllocation struct {
+	BatchID    int       `json:"batchId"`
+	Quantity   float64   `json:"quantity"`
+	BestBefore time.Time `json:"bestBefore"`
+}
+
+// ProductVariant is a purchasable option of a product, such as a 500g or 1kg bag,
+// with its own price and stock.
+type ProductVariant struct {
+	ID        int       `json:"id"`
+	ProductID int       `json:"productId"`
+	Name      string    `json:"name"`
+	Price     float64   `json:"price"`
+	Quantity  float64   `json:"quantity"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Units a product can be sold in.
+var Units = map[string]bool{
+	"each": true, "kg": true, "g": true, "lb": true, "litre": true,
+	"ml": true, "dozen": true, "bunch": true, "box": true,
+}
+
+// PriceChange is one entry in a product's (or variant's) price history.
+type PriceChange struct {
+	ID        int       `json:"id"`
+	ProductID int       `json:"productId"`
+	VariantID *int      `json:"variantId,omitempty"`
+	Price     float64   `json:"price"`
+	ChangedAt time.Time `json:"changedAt"`
+}
+
+// Product lifecycle states. Only published products are listed and can be ordered;
+// archived replaces deletion so past orders keep their product.
+const (
+	ProductStatusDraft     = "draft"
+	ProductStatusPublished = "published"
+	ProductStatusPaused    = "paused"
+	ProductStatusArchived  = "archived"
+)
+
+var ProductStatuses = map[string]bool{
+	ProductStatusDraft:     true,
+	ProductStatusPublished: true,
+	ProductStatusPaused:    true,
+	ProductStatusArchived:  true,
+}
+
+type ProductImage struct {
+	ID            int               `json:"id"`
+	ProductID     int               `json:"productId"`
+	StoragePrefix string            `json:"-"`
+	OriginalExt   string            `json:"-"`
+	VariantExt    string            `json:"-"`
+	Width         int               `json:"width"`
+	Height        int               `json:"height"`
+	Position      int               `json:"position"`
+	URLs          map[string]string `json:"urls"`
+	CreatedAt     time.Time         `json:"createdAt"`
+}
+
+// BlobKey is the storage key of one rendition ("original", "thumb", ...) of the image.
+func (i ProductImage) BlobKey(size string) string {
+	if size == "original" {
+		return i.StoragePrefix + "/original" + i.OriginalExt
+	}
+	return i.StoragePrefix + "/" + size + i.VariantExt
 }
 
 type Order struct {
@@ -31,16 +227,191 @@ type Order struct {
 	Status     string      `json:"status"`
 	CreatedAt  time.Time   `json:"createdAt"`
 	Items      []OrderItem `json:"items"`
+	Fulfilment *Fulfilment `json:"fulfilment,omitempty"`
+	FoodMiles  *FoodMiles  `json:"foodMiles,omitempty"`
+}
+
+// FoodMiles estimates how far an order travels from the producer to the pickup point
+// or delivery address, and the CO2 that journey emits. For pickups the buyer's trip
+// to the pickup point is not included.
+type FoodMiles struct {
+	DistanceMeters float64 `json:"distanceMeters"`
+	Miles          float64 `json:"miles"`
+	CO2Kg          float64 `json:"co2Kg"`
+}
+
+// Fulfilment is how an order reaches the buyer, snapshotted when the order is placed.
+// The fee is included in the order's total price.
+type Fulfilment struct {
+	Type             string   `json:"type"`
+	PickupPointID    *int     `json:"pickupPointId,omitempty"`
+	DeliveryOptionID *int     `json:"deliveryOptionId,omitempty"`
+	DeliveryZone     string   `json:"deliveryZone,omitempty"`
+	Name             string   `json:"name"`
+	Address          string   `json:"address"`
+	Latitude         *float64 `json:"latitude,omitempty"`
+	Longitude        *float64 `json:"longitude,omitempty"`
+	Fee              float64  `json:"fee"`
+	// The booked time slot, when the pickup point or delivery option uses slots.
+	SlotID       *int       `json:"slotId,omitempty"`
+	SlotStartsAt *time.Time `json:"slotStartsAt,omitempty"`
+	SlotEndsAt   *time.Time `json:"slotEndsAt,omitempty"`
+}
+
+// TimeSlot is a window in which a limited number of orders can be collected from a
+// pickup point or delivered by a delivery option.
+type TimeSlot struct {
+	ID               int       `json:"id"`
+	ProducerID       int       `json:"producerId"`
+	PickupPointID    *int      `json:"pickupPointId,omitempty"`
+	DeliveryOptionID *int      `json:"deliveryOptionId,omitempty"`
+	StartsAt         time.Time `json:"startsAt"`
+	EndsAt           time.Time `json:"endsAt"`
+	Capacity         int       `json:"capacity"`
+	Booked           int       `json:"booked"`
+	CreatedAt        time.Time `json:"createdAt"`
+}
+
+type RescheduleOrderInput struct {
+	SlotID int `json:"slotId"`
+}
+
+const (
+	FulfilmentPickup   = "pickup"
+	FulfilmentDelivery = "delivery"
+)
+
+// PickupPoint is a place buyers can collect a producer's orders from.
+type PickupPoint struct {
+	ID             int            `json:"id"`
+	ProducerID     int            `json:"producerId"`
+	Name           string         `json:"name"`
+	Address        string         `json:"address"`
+	Latitude       float64        `json:"latitude"`
+	Longitude      float64        `json:"longitude"`
+	OpeningWindows []OpeningHours `json:"openingWindows"`
+	Active         bool           `json:"active"`
+	CreatedAt      time.Time      `json:"createdAt"`
+}
+
+// DeliveryOption is a producer's delivery service. It covers either its uploaded
+// zones, each with its own fee, or addresses within RadiusMeters of the producer's
+// storefront at Fee.
+type DeliveryOption struct {
+	ID           int       `json:"id"`
+	ProducerID   int       `json:"producerId"`
+	Name         string    `json:"name"`
+	RadiusMeters *int      `json:"radiusMeters,omitempty"`
+	Fee          float64   `json:"fee"`
+	MinimumOrder float64   `json:"minimumOrder"`
+	Active       bool      `json:"active"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// DeliveryZone is an area a delivery option covers, stored as a PostGIS geography.
+// Area is a GeoJSON Polygon or MultiPolygon. A zone without a Fee charges the
+// option's fee.
+type DeliveryZone struct {
+	ID               int             `json:"id"`
+	DeliveryOptionID int             `json:"deliveryOptionId"`
+	Name             string          `json:"name"`
+	Fee              *float64        `json:"fee,omitempty"`
+	Area             json.RawMessage `json:"area"`
 }
 
+// OrderItem snapshots the product's name, unit and price when the order is placed,
+// so later edits to the product don't rewrite past orders.
 type OrderItem struct {
-	ID        int     `json:"id"`
-	OrderID   int     `json:"orderId"`
-	ProductID int     `json:"productId"`
-	Quantity  int     `json:"quantity"`
-	Price     float64 `json:"price"`
+	ID          int     `json:"id"`
+	OrderID     int     `json:"orderId"`
+	ProductID   int     `json:"productId"`
+	VariantID   *int    `json:"variantId,omitempty"`
+	ProductName string  `json:"productName"`
+	VariantName string  `json:"variantName,omitempty"`
+	Unit        string  `json:"unit"`
+	Quantity    float64 `json:"quantity"`
+	Price       float64 `json:"price"`
+
+	Preorder bool              `json:"preorder,omitempty"`
+	Batches  []BatchAllocation `json:"batches,omitempty"`
+}
+
+// Reservation holds stock for a buyer during checkout until it is confirmed as an
+// order, released, or expires.
+type Reservation struct {
+	ID         int         `json:"id"`
+	BuyerID    int         `json:"buyerId"`
+	ProducerID int         `json:"producerId"`
+	TotalPrice float64     `json:"totalPrice"`
+	Status     string      `json:"status"`
+	ExpiresAt  time.Time   `json:"expiresAt"`
+	CreatedAt  time.Time   `json:"createdAt"`
+	OrderID    *int        `json:"orderId,omitempty"`
+	Items      []OrderItem `json:"items"`
+	Fulfilment *Fulfilment `json:"fulfilment,omitempty"`
+}
+
+// Order states set by the server. Pre-orders wait in OrderPreordered until the
+// harvest is released, then become OrderPending or, if the harvest fell short,
+// OrderCancelled.
+const (
+	OrderPending    = "pending"
+	OrderPreordered = "preordered"
+	OrderCancelled  = "cancelled"
+)
+
+// Reservation states. Expired reservations are released by the sweeper job.
+const (
+	ReservationActive    = "active"
+	ReservationConfirmed = "confirmed"
+	ReservationReleased  = "released"
+	ReservationExpired   = "expired"
+)
+
+// SubscriptionPlan is a recurring box a producer offers, such as a weekly veg box,
+// with fixed contents and price.
+type SubscriptionPlan struct {
+	ID            int              `json:"id"`
+	ProducerID    int              `json:"producerId"`
+	Name          string           `json:"name"`
+	Description   string           `json:"description"`
+	Price         float64          `json:"price"`
+	Cadence       string           `json:"cadence"`
+	PickupDay     int              `json:"pickupDay"`
+	PickupPointID *int             `json:"pickupPointId"`
+	Active        bool             `json:"active"`
+	CreatedAt     time.Time        `json:"createdAt"`
+	Items         []OrderItemInput `json:"items"`
+}
+
+// UpdateSubscriptionPlanInput changes a plan for the pickups still to come. Cadence,
+// pickup day and items stay fixed because subscribers signed up for them.
+type UpdateSubscriptionPlanInput struct {
+	Name          *string  `json:"name"`
+	Description   *string  `json:"description"`
+	Price         *float64 `json:"price"`
+	PickupPointID *int     `json:"pickupPointId"`
 }
 
+// Cadences a subscription plan can run on. PickupDay is a weekday, 0 for Sunday.
+var Cadences = map[string]bool{"weekly": true, "fortnightly": true, "monthly": true}
+
+type Subscription struct {
+	ID           int               `json:"id"`
+	PlanID       int               `json:"planId"`
+	BuyerID      int               `json:"buyerId"`
+	Status       string            `json:"status"`
+	NextPickupOn time.Time         `json:"nextPickupOn"`
+	CreatedAt    time.Time         `json:"createdAt"`
+	Plan         *SubscriptionPlan `json:"plan,omitempty"`
+}
+
+const (
+	SubscriptionActive    = "active"
+	SubscriptionPaused    = "paused"
+	SubscriptionCancelled = "cancelled"
+)
+
 type Review struct {
 	ID        int       `json:"id"`
 	ProductID int       `json:"productId"`
@@ -50,6 +421,88 @@ type Review struct {
 	CreatedAt time.Time `json:"createdAt"`
 }
 
+type APIKey struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"userId"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	RevokedAt  *time.Time `json:"revokedAt"`
+}
+
+type Session struct {
+	ID         string    `json:"id"`
+	UserID     int       `json:"-"`
+	Device     string    `json:"device"`
+	IPAddress  string    `json:"ipAddress"`
+	UserAgent  string    `json:"userAgent"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	Current    bool      `json:"current"`
+}
+
+type UserIdentity struct {
+	Provider  string    `json:"provider"`
+	Subject   string    `json:"subject"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// SavedSearch alerts its owner when a product matching it is published. Empty Query
+// and Category match anything.
+type SavedSearch struct {
+	ID           int       `json:"id"`
+	UserID       int       `json:"userId"`
+	Name         string    `json:"name"`
+	Query        string    `json:"query"`
+	Category     string    `json:"category"`
+	Address      string    `json:"address,omitempty"`
+	Latitude     float64   `json:"latitude"`
+	Longitude    float64   `json:"longitude"`
+	RadiusMeters int       `json:"radiusMeters"`
+	MaxPrice     *float64  `json:"maxPrice,omitempty"`
+	EmailDigest  bool      `json:"emailDigest"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// SavedSearchMatch is a newly published product that matched a saved search.
+type SavedSearchMatch struct {
+	SearchID    int       `json:"searchId"`
+	SearchName  string    `json:"searchName"`
+	UserID      int       `json:"-"`
+	ProductID   int       `json:"productId"`
+	ProductName string    `json:"productName"`
+	Price       float64   `json:"price"`
+	Unit        string    `json:"unit"`
+	MatchedAt   time.Time `json:"matchedAt"`
+}
+
+// SearchDigest is one user's matches not yet sent by email.
+type SearchDigest struct {
+	UserID    int
+	Email     string
+	Name      string
+	SearchIDs []int
+	Matches   []SavedSearchMatch
+}
+
+// UserExport is the personal data archive returned by GET /users/me/export.
+type UserExport struct {
+	ExportedAt time.Time      `json:"exportedAt"`
+	Profile    User           `json:"profile"`
+	Identities []UserIdentity `json:"identities"`
+	APIKeys    []APIKey       `json:"apiKeys"`
+	Orders     []Order        `json:"orders"`
+	Reviews    []Review       `json:"reviews"`
+
+	ProducerProfile *ProducerProfile `json:"producerProfile,omitempty"`
+	SavedSearches   []SavedSearch    `json:"savedSearches"`
+}
+
 // Input Structs
 type RegisterUserInput struct {
 	Name     string `json:"name"`
@@ -61,14 +514,46 @@ type RegisterUserInput struct {
 type LoginUserInput struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	Device   string `json:"device"`
+}
+
+type MFACodeInput struct {
+	Code string `json:"code"`
+}
+
+type VerifyMFAInput struct {
+	MFAToken string `json:"mfaToken"`
+	Code     string `json:"code"`
+	Device   string `json:"device"`
 }
 
 type CreateOrderInput struct {
-	ProducerID int `json:"producerId"`
-	Items      []struct {
-		ProductID int `json:"productId"`
-		Quantity  int `json:"quantity"`
-	} `json:"items"`
+	ProducerID int              `json:"producerId"`
+	Items      []OrderItemInput `json:"items"`
+	// Exactly one of PickupPointID or DeliveryOptionID must be chosen; delivery also
+	// needs DeliveryAddress.
+	PickupPointID    *int             `json:"pickupPointId"`
+	DeliveryOptionID *int             `json:"deliveryOptionId"`
+	DeliveryAddress  *DeliveryAddress `json:"deliveryAddress"`
+	// SlotID is required when the chosen pickup point or delivery option has
+	// upcoming time slots.
+	SlotID *int `json:"slotId"`
+	// TotalPrice, when set by the server, is an agreed price for the whole order (such
+	// as a subscription box) that replaces the sum of the item prices.
+	TotalPrice *float64 `json:"-"`
+}
+
+// DeliveryAddress is geocoded from Address when Latitude and Longitude are left out.
+type DeliveryAddress struct {
+	Address   string  `json:"address"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+type OrderItemInput struct {
+	ProductID int     `json:"productId"`
+	VariantID *int    `json:"variantId"`
+	Quantity  float64 `json:"quantity"`
 }
 
 type CreateReviewInput struct {
@@ -80,13 +565,75 @@ type UpdateUserInput struct {
 	Name *string `json:"name"`
 }
 
+// CreateProductInput is a new product. Address is an address or postcode geocoded
+// into Latitude and Longitude when they are left out; it is not stored.
+type CreateProductInput struct {
+	Product
+	Address string `json:"address"`
+}
+
+// UpdateProductInput changes the fields that are set. Latitude and Longitude move the
+// product and must be given together; Address is geocoded when they are left out.
 type UpdateProductInput struct {
-	Name        *string  `json:"name"`
-	Description *string  `json:"description"`
-	Price       *float64 `json:"price"`
-	Quantity    *int     `json:"quantity"`
+	SKU          *string     `json:"sku"`
+	Name         *string     `json:"name"`
+	Description  *string     `json:"description"`
+	Category     *string     `json:"category"`
+	Price        *float64    `json:"price"`
+	Quantity     *float64    `json:"quantity"`
+	Unit         *string     `json:"unit"`
+	QuantityStep *float64    `json:"quantityStep"`
+	Status       *string     `json:"status"`
+	Markdowns    *[]Markdown `json:"markdowns"`
+	Latitude     *float64    `json:"latitude"`
+	Longitude    *float64    `json:"longitude"`
+	Address      *string     `json:"address"`
+}
+
+// ProductImport is one row of a catalogue import. Product is created as given when
+// its SKU is new. An existing product only gets the fields set in Update, and its
+// location when HasLocation is set.
+type ProductImport struct {
+	Product     Product
+	Update      UpdateProductInput
+	HasLocation bool
+}
+
+// PreorderInput takes AvailableFrom as YYYY-MM-DD.
+type PreorderInput struct {
+	AvailableFrom string  `json:"availableFrom"`
+	Limit         float64 `json:"limit"`
+}
+
+type ReleaseHarvestInput struct {
+	Quantity float64 `json:"quantity"`
+}
+
+// PreorderRelease is the outcome for one pre-order when its harvest was released.
+type PreorderRelease struct {
+	OrderID int
+	BuyerID int
+	Status  string
+}
+
+// CreateBatchInput takes dates as YYYY-MM-DD.
+type CreateBatchInput struct {
+	Quantity    float64 `json:"quantity"`
+	HarvestedOn string  `json:"harvestedOn"`
+	BestBefore  string  `json:"bestBefore"`
+}
+
+type ProductVariantInput struct {
+	Name     *string  `json:"name"`
+	Price    *float64 `json:"price"`
+	Quantity *float64 `json:"quantity"`
 }
 
 type UpdateOrderStatusInput struct {
 	Status string `json:"status"`
-}
\ No newline at end of file
+}
+
+type CreateAPIKeyInput struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
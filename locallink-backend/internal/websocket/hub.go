@@ -7,6 +7,12 @@ type Hub struct {
 	Broadcast  chan []byte
 	Register   chan *Client
 	Unregister chan *Client
+	direct     chan directMessage
+}
+
+type directMessage struct {
+	userID  int
+	message []byte
 }
 
 func NewHub() *Hub {
@@ -15,9 +21,16 @@ func NewHub() *Hub {
 		Register:   make(chan *Client),
 		Unregister: make(chan *Client),
 		Clients:    make(map[int]*Client),
+		direct:     make(chan directMessage, 256),
 	}
 }
 
+// SendToUser queues a message for one user's connection, if they have one. It is safe
+// to call from any goroutine, such as background jobs.
+func (h *Hub) SendToUser(userID int, message []byte) {
+	h.direct <- directMessage{userID: userID, message: message}
+}
+
 func (h *Hub) Run() {
 	for {
 		select {
@@ -30,6 +43,15 @@ func (h *Hub) Run() {
 				close(client.Send)
 				log.Printf("Client unregistered: UserID %d", client.UserID)
 			}
+		case dm := <-h.direct:
+			if client, ok := h.Clients[dm.userID]; ok {
+				select {
+				case client.Send <- dm.message:
+				default:
+					close(client.Send)
+					delete(h.Clients, client.UserID)
+				}
+			}
 		case message := <-h.Broadcast:
 			for _, client := range h.Clients {
 				select {
@@ -0,0 +1,6 @@
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS deletion_scheduled_for TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS anonymized_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_users_deletion_due ON users(deletion_scheduled_for)
+    WHERE deletion_scheduled_for IS NOT NULL AND anonymized_at IS NULL;
@@ -0,0 +1,19 @@
+ALTER TABLE products
+    ALTER COLUMN quantity TYPE NUMERIC(12, 3),
+    ADD COLUMN IF NOT EXISTS unit TEXT NOT NULL DEFAULT 'each',
+    ADD COLUMN IF NOT EXISTS quantity_step NUMERIC(12, 3) NOT NULL DEFAULT 1 CHECK (quantity_step > 0);
+
+CREATE TABLE IF NOT EXISTS product_variants (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    price NUMERIC(10, 2) NOT NULL,
+    quantity NUMERIC(12, 3) NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_variants_product ON product_variants(product_id);
+
+ALTER TABLE order_items
+    ALTER COLUMN quantity TYPE NUMERIC(12, 3),
+    ADD COLUMN IF NOT EXISTS variant_id INTEGER REFERENCES product_variants(id) ON DELETE SET NULL;
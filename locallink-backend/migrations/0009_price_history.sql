@@ -0,0 +1,37 @@
+CREATE TABLE IF NOT EXISTS product_price_history (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    variant_id INTEGER REFERENCES product_variants(id) ON DELETE CASCADE,
+    price NUMERIC(10, 2) NOT NULL,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_price_history_product ON product_price_history(product_id, changed_at);
+
+-- Seed the history with today's prices so every product has a starting point.
+INSERT INTO product_price_history (product_id, price)
+SELECT id, price FROM products p
+WHERE NOT EXISTS (SELECT 1 FROM product_price_history h WHERE h.product_id = p.id AND h.variant_id IS NULL);
+
+INSERT INTO product_price_history (product_id, variant_id, price)
+SELECT product_id, id, price FROM product_variants v
+WHERE NOT EXISTS (SELECT 1 FROM product_price_history h WHERE h.variant_id = v.id);
+
+ALTER TABLE order_items
+    ADD COLUMN IF NOT EXISTS product_name TEXT,
+    ADD COLUMN IF NOT EXISTS variant_name TEXT,
+    ADD COLUMN IF NOT EXISTS unit TEXT;
+
+-- Best effort backfill for orders placed before snapshots existed.
+UPDATE order_items oi SET product_name = p.name, unit = p.unit
+FROM products p WHERE oi.product_id = p.id AND oi.product_name IS NULL;
+
+UPDATE order_items oi SET variant_name = v.name
+FROM product_variants v WHERE oi.variant_id = v.id AND oi.variant_name IS NULL;
+
+UPDATE order_items SET product_name = '' WHERE product_name IS NULL;
+UPDATE order_items SET unit = 'each' WHERE unit IS NULL;
+
+ALTER TABLE order_items
+    ALTER COLUMN product_name SET NOT NULL,
+    ALTER COLUMN unit SET NOT NULL;
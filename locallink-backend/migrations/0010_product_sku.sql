@@ -0,0 +1,3 @@
+ALTER TABLE products ADD COLUMN IF NOT EXISTS sku TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_products_producer_sku ON products(producer_id, sku) WHERE sku IS NOT NULL;
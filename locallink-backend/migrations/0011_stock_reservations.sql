@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS stock_reservations (
+    id SERIAL PRIMARY KEY,
+    buyer_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    producer_id INTEGER NOT NULL REFERENCES users(id),
+    total_price NUMERIC(10, 2) NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    confirmed_at TIMESTAMPTZ,
+    released_at TIMESTAMPTZ,
+    order_id INTEGER REFERENCES orders(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_reservations_open ON stock_reservations(expires_at)
+    WHERE confirmed_at IS NULL AND released_at IS NULL;
+
+-- variant_id deliberately has no foreign key: releasing stock for a variant that has
+-- since been deleted is simply a no-op.
+CREATE TABLE IF NOT EXISTS stock_reservation_items (
+    id SERIAL PRIMARY KEY,
+    reservation_id INTEGER NOT NULL REFERENCES stock_reservations(id) ON DELETE CASCADE,
+    product_id INTEGER NOT NULL REFERENCES products(id),
+    variant_id INTEGER,
+    product_name TEXT NOT NULL,
+    variant_name TEXT,
+    unit TEXT NOT NULL,
+    quantity NUMERIC(12, 3) NOT NULL,
+    price NUMERIC(10, 2) NOT NULL
+);
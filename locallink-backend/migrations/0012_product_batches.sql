@@ -0,0 +1,16 @@
+ALTER TABLE products ADD COLUMN IF NOT EXISTS markdowns JSONB NOT NULL DEFAULT '[]';
+
+CREATE TABLE IF NOT EXISTS product_batches (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    quantity NUMERIC(12, 3) NOT NULL CHECK (quantity >= 0),
+    harvested_on DATE,
+    best_before DATE NOT NULL,
+    expired_quantity NUMERIC(12, 3) NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_batches_fifo ON product_batches(product_id, best_before) WHERE quantity > 0;
+
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS batches JSONB;
+ALTER TABLE stock_reservation_items ADD COLUMN IF NOT EXISTS batches JSONB;
@@ -0,0 +1,10 @@
+ALTER TABLE products
+    ADD COLUMN IF NOT EXISTS available_from DATE,
+    ADD COLUMN IF NOT EXISTS preorder_limit NUMERIC(12, 3),
+    ADD COLUMN IF NOT EXISTS preordered_quantity NUMERIC(12, 3) NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS released_at TIMESTAMPTZ;
+
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS preorder BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE stock_reservation_items ADD COLUMN IF NOT EXISTS preorder BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_orders_preordered ON orders(created_at) WHERE status = 'preordered';
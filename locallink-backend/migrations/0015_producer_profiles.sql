@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS producer_profiles (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    farm_name TEXT NOT NULL,
+    bio TEXT NOT NULL DEFAULT '',
+    practices TEXT[] NOT NULL DEFAULT '{}',
+    certifications TEXT[] NOT NULL DEFAULT '{}',
+    opening_hours JSONB NOT NULL DEFAULT '[]',
+    pickup_address TEXT NOT NULL DEFAULT '',
+    location GEOGRAPHY(Point, 4326) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_producer_profiles_location ON producer_profiles USING GIST (location);
@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS pickup_points (
+    id SERIAL PRIMARY KEY,
+    producer_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    address TEXT NOT NULL,
+    location GEOGRAPHY(Point, 4326) NOT NULL,
+    opening_windows JSONB NOT NULL DEFAULT '[]',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_pickup_points_producer ON pickup_points(producer_id) WHERE active;
+
+CREATE TABLE IF NOT EXISTS delivery_options (
+    id SERIAL PRIMARY KEY,
+    producer_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    radius_meters INTEGER NOT NULL CHECK (radius_meters > 0),
+    fee NUMERIC(10, 2) NOT NULL DEFAULT 0 CHECK (fee >= 0),
+    minimum_order NUMERIC(10, 2) NOT NULL DEFAULT 0 CHECK (minimum_order >= 0),
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_delivery_options_producer ON delivery_options(producer_id) WHERE active;
+
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS pickup_point_id INTEGER REFERENCES pickup_points(id),
+    ADD COLUMN IF NOT EXISTS delivery_option_id INTEGER REFERENCES delivery_options(id),
+    ADD COLUMN IF NOT EXISTS fulfilment JSONB;
+
+ALTER TABLE stock_reservations ADD COLUMN IF NOT EXISTS fulfilment JSONB;
+
+ALTER TABLE subscription_plans ADD COLUMN IF NOT EXISTS pickup_point_id INTEGER REFERENCES pickup_points(id);
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS time_slots (
+    id SERIAL PRIMARY KEY,
+    producer_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    pickup_point_id INTEGER REFERENCES pickup_points(id) ON DELETE CASCADE,
+    delivery_option_id INTEGER REFERENCES delivery_options(id) ON DELETE CASCADE,
+    starts_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ NOT NULL,
+    capacity INTEGER NOT NULL CHECK (capacity > 0),
+    booked INTEGER NOT NULL DEFAULT 0 CHECK (booked >= 0 AND booked <= capacity),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (ends_at > starts_at),
+    CHECK ((pickup_point_id IS NULL) <> (delivery_option_id IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_time_slots_pickup_point ON time_slots(pickup_point_id, starts_at);
+CREATE INDEX IF NOT EXISTS idx_time_slots_delivery_option ON time_slots(delivery_option_id, starts_at);
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS slot_id INTEGER REFERENCES time_slots(id);
@@ -0,0 +1,14 @@
+-- Delivery options can cover drawn zones instead of a radius.
+ALTER TABLE delivery_options ALTER COLUMN radius_meters DROP NOT NULL;
+
+CREATE TABLE IF NOT EXISTS delivery_zones (
+    id SERIAL PRIMARY KEY,
+    delivery_option_id INTEGER NOT NULL REFERENCES delivery_options(id) ON DELETE CASCADE,
+    name TEXT NOT NULL DEFAULT '',
+    area GEOGRAPHY(MultiPolygon, 4326) NOT NULL,
+    fee NUMERIC(10, 2) NOT NULL DEFAULT 0 CHECK (fee >= 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_delivery_zones_area ON delivery_zones USING GIST (area);
+CREATE INDEX IF NOT EXISTS idx_delivery_zones_option ON delivery_zones(delivery_option_id);
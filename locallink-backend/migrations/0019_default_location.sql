@@ -0,0 +1,3 @@
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS default_location GEOGRAPHY(Point, 4326),
+    ADD COLUMN IF NOT EXISTS default_location_label TEXT;
@@ -0,0 +1,31 @@
+ALTER TABLE products ADD COLUMN IF NOT EXISTS category TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_products_category ON products(category) WHERE category <> '';
+
+CREATE TABLE IF NOT EXISTS saved_searches (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    query TEXT NOT NULL DEFAULT '',
+    category TEXT NOT NULL DEFAULT '',
+    location GEOGRAPHY(Point, 4326) NOT NULL,
+    radius_meters INTEGER NOT NULL CHECK (radius_meters > 0),
+    max_price NUMERIC(10, 2) CHECK (max_price >= 0),
+    email_digest BOOLEAN NOT NULL DEFAULT FALSE,
+    last_digest_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_saved_searches_user ON saved_searches(user_id);
+CREATE INDEX IF NOT EXISTS idx_saved_searches_location ON saved_searches USING GIST (location);
+
+-- One row per product a search has alerted on, so each product alerts once per search.
+CREATE TABLE IF NOT EXISTS saved_search_matches (
+    search_id INTEGER NOT NULL REFERENCES saved_searches(id) ON DELETE CASCADE,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    matched_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    emailed_at TIMESTAMPTZ,
+    PRIMARY KEY (search_id, product_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_saved_search_matches_pending ON saved_search_matches(search_id) WHERE emailed_at IS NULL;
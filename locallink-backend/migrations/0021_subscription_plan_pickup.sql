@@ -0,0 +1,5 @@
+-- Plans created before pickup points existed collect from the producer's oldest
+-- active pickup point. Producers without one are asked to choose when the plan runs.
+UPDATE subscription_plans sp SET pickup_point_id = (
+    SELECT p.id FROM pickup_points p WHERE p.producer_id = sp.producer_id AND p.active ORDER BY p.created_at, p.id LIMIT 1
+) WHERE sp.pickup_point_id IS NULL;
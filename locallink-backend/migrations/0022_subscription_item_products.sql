@@ -0,0 +1,4 @@
+-- Deleting a product drops it from the plans that include it, as deleting a variant does.
+ALTER TABLE subscription_plan_items
+    DROP CONSTRAINT IF EXISTS subscription_plan_items_product_id_fkey,
+    ADD CONSTRAINT subscription_plan_items_product_id_fkey FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE;
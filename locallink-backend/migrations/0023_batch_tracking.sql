@@ -0,0 +1,5 @@
+-- Batch tracking is a property of the product, not inferred from whichever batches
+-- happen to be left, so stock can't slip between the two models.
+ALTER TABLE products ADD COLUMN IF NOT EXISTS batch_tracked BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE products p SET batch_tracked = TRUE WHERE EXISTS (SELECT 1 FROM product_batches b WHERE b.product_id = p.id);
@@ -0,0 +1,2 @@
+-- The time step of the last accepted TOTP code, so a code can't be used twice.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_last_step BIGINT;
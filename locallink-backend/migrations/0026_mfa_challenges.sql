@@ -0,0 +1,6 @@
+-- MFA challenge tokens that have completed a login, kept until they expire so each
+-- one can only be used once.
+CREATE TABLE IF NOT EXISTS mfa_challenges_used (
+    id TEXT PRIMARY KEY,
+    expires_at TIMESTAMPTZ NOT NULL
+);
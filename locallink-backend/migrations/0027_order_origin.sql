@@ -0,0 +1,8 @@
+-- Where an order's food set off from, snapshotted when it is placed so its food miles
+-- don't change when the producer moves their storefront or a product.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS origin GEOGRAPHY(Point, 4326);
+
+UPDATE orders o SET origin = COALESCE(
+    (SELECT pp.location FROM producer_profiles pp WHERE pp.user_id = o.producer_id),
+    (SELECT p.location FROM order_items oi JOIN products p ON p.id = oi.product_id WHERE oi.order_id = o.id ORDER BY oi.id LIMIT 1))
+WHERE o.origin IS NULL;
@@ -0,0 +1,4 @@
+-- A zone without its own fee charges the delivery option's fee. Zones saved before
+-- this kept the old default of 0 and stay free until they are uploaded again.
+ALTER TABLE delivery_zones ALTER COLUMN fee DROP NOT NULL;
+ALTER TABLE delivery_zones ALTER COLUMN fee DROP DEFAULT;
@@ -0,0 +1,2 @@
+-- Map viewports filter on the planar point, which the geography index can't serve.
+CREATE INDEX IF NOT EXISTS idx_products_published_geometry ON products USING GIST ((location::geometry)) WHERE status = 'published';